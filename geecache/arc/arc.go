@@ -0,0 +1,233 @@
+// Package arc 实现了自适应替换缓存(Adaptive Replacement Cache)。
+// t1保存只访问过一次的记录，t2保存访问过多次的记录，b1和b2分别是从t1、t2淘汰的记录的key(幽灵记录)。
+// 命中幽灵记录时调整t1的目标大小p，从而在最近访问和访问频率之间自适应。
+// 原算法按记录条数计算容量，这里统一按字节数计算，幽灵记录按被淘汰时的大小计算
+package arc
+
+import (
+	"container/list"
+	"time"
+
+	"geektutu/geecache/policy"
+)
+
+type Cache struct {
+	maxBytes int64
+	// t1的目标字节数
+	p         int64
+	t1, t2    *lruList
+	b1, b2    *lruList
+	cache     map[string]*list.Element
+	OnEvicted func(key string, value policy.Value)                       // 只在超出容量淘汰记录时调用
+	OnRemoved func(key string, value policy.Value, reason policy.Reason) // 记录因为任何原因被删除时调用，reason说明原因
+}
+
+var _ policy.Policy = (*Cache)(nil)
+
+// lruList 是带字节数统计的链表，头部是最近访问的记录
+type lruList struct {
+	*list.List
+	nBytes int64
+}
+
+type entry struct {
+	policy.Entry
+	// 记录所在的链表
+	where *lruList
+	// 幽灵记录的Value为nil，size保存被淘汰时的大小
+	size int64
+}
+
+// New 创建一个Cache，maxBytes为0表示不限制大小
+func New(maxBytes int64, onEvicted func(string, policy.Value)) *Cache {
+	return &Cache{
+		maxBytes:  maxBytes,
+		t1:        &lruList{List: list.New()},
+		t2:        &lruList{List: list.New()},
+		b1:        &lruList{List: list.New()},
+		b2:        &lruList{List: list.New()},
+		cache:     make(map[string]*list.Element),
+		OnEvicted: onEvicted,
+	}
+}
+
+func (c *Cache) Get(key string) (policy.Value, bool) {
+	ele, ok := c.cache[key]
+	if !ok {
+		return nil, false
+	}
+	e := ele.Value.(*entry)
+	if c.ghost(e) {
+		return nil, false
+	}
+	if e.Expired(time.Now()) {
+		c.removeElement(ele, policy.Expired)
+		return nil, false
+	}
+	// 再次访问的记录进入t2
+	c.move(ele, c.t2)
+	return e.Value, true
+}
+
+func (c *Cache) Add(key string, value policy.Value) {
+	c.AddWithExpire(key, value, time.Time{})
+}
+
+func (c *Cache) AddWithExpire(key string, value policy.Value, expire time.Time) {
+	size := int64(len(key)) + int64(value.Len())
+	if ele, ok := c.cache[key]; ok {
+		e := ele.Value.(*entry)
+		switch e.where {
+		case c.t1, c.t2:
+			e.Value, e.Expire = value, expire
+			c.setSize(e, size)
+			c.move(ele, c.t2)
+			c.evict(false)
+			return
+		case c.b1:
+			// 最近淘汰的记录又被访问，说明t1太小
+			c.p = min(c.p+ratio(c.b2.nBytes, c.b1.nBytes)*size, c.maxBytes)
+		case c.b2:
+			// 频繁访问的记录被淘汰后又被访问，说明t2太小
+			c.p = max(c.p-ratio(c.b1.nBytes, c.b2.nBytes)*size, 0)
+		}
+		inB2 := e.where == c.b2
+		e.Value, e.Expire = value, expire
+		c.setSize(e, size)
+		c.move(ele, c.t2)
+		c.evict(inB2)
+		return
+	}
+	e := &entry{Entry: policy.Entry{Key: key, Value: value, Expire: expire}, where: c.t1, size: size}
+	c.t1.nBytes += size
+	c.cache[key] = c.t1.PushFront(e)
+	c.evict(false)
+	c.trimGhosts()
+}
+
+func (c *Cache) Remove(key string) bool {
+	ele, ok := c.cache[key]
+	if !ok || c.ghost(ele.Value.(*entry)) {
+		return false
+	}
+	c.removeElement(ele, policy.Removed)
+	return true
+}
+
+func (c *Cache) RemoveExpired() int {
+	now := time.Now()
+	n := 0
+	for _, l := range []*lruList{c.t1, c.t2} {
+		for ele := l.Back(); ele != nil; {
+			prev := ele.Prev()
+			if ele.Value.(*entry).Expired(now) {
+				c.removeElement(ele, policy.Expired)
+				n++
+			}
+			ele = prev
+		}
+	}
+	return n
+}
+
+func (c *Cache) Len() int {
+	return c.t1.Len() + c.t2.Len()
+}
+
+func (c *Cache) Bytes() int64 {
+	return c.t1.nBytes + c.t2.nBytes
+}
+
+func (c *Cache) ghost(e *entry) bool {
+	return e.where == c.b1 || e.where == c.b2
+}
+
+func (c *Cache) setSize(e *entry, size int64) {
+	e.where.nBytes += size - e.size
+	e.size = size
+}
+
+// move 把记录移动到to的头部
+func (c *Cache) move(ele *list.Element, to *lruList) {
+	e := ele.Value.(*entry)
+	if e.where == to {
+		to.MoveToFront(ele)
+		return
+	}
+	e.where.Remove(ele)
+	e.where.nBytes -= e.size
+	e.where = to
+	to.nBytes += e.size
+	c.cache[e.Key] = to.PushFront(e)
+}
+
+// evict 淘汰记录直到不超过容量，t1超过目标大小p时淘汰t1，否则淘汰t2
+func (c *Cache) evict(inB2 bool) {
+	if c.maxBytes == 0 {
+		return
+	}
+	for c.Bytes() > c.maxBytes {
+		if c.t1.Len() > 0 && (c.t1.nBytes > c.p || (inB2 && c.t1.nBytes == c.p) || c.t2.Len() == 0) {
+			c.demote(c.t1.Back(), c.b1)
+		} else {
+			c.demote(c.t2.Back(), c.b2)
+		}
+	}
+}
+
+// demote 把记录淘汰为幽灵记录
+func (c *Cache) demote(ele *list.Element, to *lruList) {
+	e := ele.Value.(*entry)
+	value := e.Value
+	c.move(ele, to)
+	e.Value = nil
+	policy.Notify(c.OnEvicted, c.OnRemoved, e.Key, value, policy.Evicted)
+}
+
+// trimGhosts 限制幽灵记录的大小：t1+b1不超过容量，全部记录不超过两倍容量
+func (c *Cache) trimGhosts() {
+	if c.maxBytes == 0 {
+		return
+	}
+	for c.b1.Len() > 0 && c.t1.nBytes+c.b1.nBytes > c.maxBytes {
+		c.drop(c.b1.Back())
+	}
+	for c.b2.Len() > 0 && c.Bytes()+c.b1.nBytes+c.b2.nBytes > 2*c.maxBytes {
+		c.drop(c.b2.Back())
+	}
+}
+
+func (c *Cache) drop(ele *list.Element) {
+	e := ele.Value.(*entry)
+	e.where.Remove(ele)
+	e.where.nBytes -= e.size
+	delete(c.cache, e.Key)
+}
+
+func (c *Cache) removeElement(ele *list.Element, reason policy.Reason) {
+	e := ele.Value.(*entry)
+	c.drop(ele)
+	policy.Notify(c.OnEvicted, c.OnRemoved, e.Key, e.Value, reason)
+}
+
+// ratio 返回a/b，至少为1
+func ratio(a, b int64) int64 {
+	if b == 0 {
+		return 1
+	}
+	return max(a/b, 1)
+}
+
+func min(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
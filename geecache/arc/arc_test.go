@@ -0,0 +1,66 @@
+package arc
+
+import (
+	"fmt"
+	"testing"
+)
+
+type String string
+
+func (d String) Len() int {
+	return len(d)
+}
+
+func TestGet(t *testing.T) {
+	arc := New(int64(0), nil)
+	arc.Add("key1", String("1234"))
+	if v, ok := arc.Get("key1"); !ok || string(v.(String)) != "1234" {
+		t.Fatalf("cache hit key1=1234 failed")
+	}
+	if _, ok := arc.Get("key2"); ok {
+		t.Fatalf("cache miss key2 failed")
+	}
+	if !arc.Remove("key1") || arc.Len() != 0 || arc.Bytes() != 0 {
+		t.Fatalf("remove key1 failed")
+	}
+}
+
+func TestScanResistant(t *testing.T) {
+	arc := New(int64(len("k1v1")*4), nil)
+	// k1、k2被访问过两次，进入t2
+	for _, k := range []string{"k1", "k2"} {
+		arc.Add(k, String("v"+k[1:]))
+		arc.Get(k)
+	}
+	// 只访问一次的记录只会相互淘汰
+	for i := 0; i < 10; i++ {
+		arc.Add(fmt.Sprintf("s%d", i), String("xx"))
+	}
+	for _, k := range []string{"k1", "k2"} {
+		if _, ok := arc.Get(k); !ok {
+			t.Fatalf("%s should not be evicted by scan", k)
+		}
+	}
+	if arc.Bytes() > arc.maxBytes {
+		t.Fatalf("cache exceeds %d bytes", arc.maxBytes)
+	}
+}
+
+func TestGhostHit(t *testing.T) {
+	arc := New(int64(len("k1v1")*2), nil)
+	arc.Add("k1", String("v1"))
+	arc.Get("k1")
+	arc.Add("k2", String("v2"))
+	arc.Add("k3", String("v3"))
+	if _, ok := arc.Get("k2"); ok {
+		t.Fatalf("k2 should be evicted")
+	}
+	// k2在b1中，再次添加时增大p并进入t2
+	arc.Add("k2", String("v2"))
+	if arc.p == 0 {
+		t.Fatalf("ghost hit in b1 should grow p")
+	}
+	if e := arc.cache["k2"].Value.(*entry); e.where != arc.t2 {
+		t.Fatalf("k2 should be in t2")
+	}
+}
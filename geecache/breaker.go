@@ -0,0 +1,67 @@
+package geecache
+
+import (
+	"sync"
+	"time"
+)
+
+// breaker 是每个节点的熔断器：连续失败threshold次后断开cooldown时间，
+// 断开期间PickPeer跳过该节点，把它的key交给hash环上的下一个节点。
+// 冷却结束后只放行一个请求试探，成功则恢复，失败则再次断开
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+}
+
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	if threshold <= 0 {
+		return nil
+	}
+	return &breaker{threshold: threshold, cooldown: cooldown}
+}
+
+// allow 返回当前是否可以访问该节点，b为nil表示不使用熔断
+func (b *breaker) allow() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.openUntil.IsZero() {
+		return true
+	}
+	now := time.Now()
+	if now.Before(b.openUntil) {
+		return false
+	}
+	// 冷却结束，放行这一个试探请求，在它返回结果之前其他请求仍然交给后继节点。
+	// 如果试探请求一直没有结果，再过cooldown后放行下一个
+	b.openUntil = now.Add(b.cooldown)
+	return true
+}
+
+func (b *breaker) success() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.openUntil = time.Time{}
+}
+
+func (b *breaker) failure() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openUntil = time.Now().Add(b.cooldown)
+	}
+}
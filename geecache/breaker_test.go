@@ -0,0 +1,98 @@
+package geecache
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	pb "geektutu/geecache/geecachepb"
+)
+
+func TestBreaker(t *testing.T) {
+	b := newBreaker(2, 20*time.Millisecond)
+	b.failure()
+	if !b.allow() {
+		t.Fatalf("breaker should stay closed below threshold")
+	}
+	b.failure()
+	if b.allow() {
+		t.Fatalf("breaker should open after 2 failures")
+	}
+	time.Sleep(30 * time.Millisecond)
+	if !b.allow() {
+		t.Fatalf("breaker should allow a trial after cooldown")
+	}
+	if b.allow() {
+		t.Fatalf("breaker should allow only one trial")
+	}
+	// 试探失败，再次断开
+	b.failure()
+	if b.allow() {
+		t.Fatalf("breaker should open again after failed trial")
+	}
+	time.Sleep(30 * time.Millisecond)
+	if !b.allow() {
+		t.Fatalf("breaker should allow a trial after cooldown")
+	}
+	b.success()
+	if !b.allow() || !b.allow() {
+		t.Fatalf("breaker should close after success")
+	}
+
+	disabled := newBreaker(0, time.Second)
+	disabled.failure()
+	if !disabled.allow() {
+		t.Fatalf("nil breaker should always allow")
+	}
+}
+
+func TestPickPeerBreaker(t *testing.T) {
+	p := NewHTTPPool("http://a", &HTTPPoolOption{FailureThreshold: 1, Cooldown: time.Minute})
+	p.Set("http://a", "http://b", "http://c")
+	// 找到一个属于b，且下一个节点不是自己的key
+	var key, next string
+	for i := 0; key == ""; i++ {
+		k := "key" + strconv.Itoa(i)
+		if nodes := p.peers.locator.GetN(k, 3); nodes[0] == "http://b" && nodes[1] == "http://c" {
+			key, next = k, nodes[1]
+		}
+	}
+	if peer, ok := p.PickPeer(key); !ok || peer != p.peers.getters["http://b"] {
+		t.Fatalf("expect owner b")
+	}
+	p.peers.getters["http://b"].getBreaker().failure()
+	if peer, ok := p.PickPeer(key); !ok || peer != p.peers.getters[next] {
+		t.Fatalf("expect successor %s when b is open", next)
+	}
+	// 写入和删除只能交给所属节点，所属节点熔断时返回错误
+	if _, ok, err := p.OwnerPeer(key); ok || err == nil {
+		t.Fatalf("expect error when owner b is open")
+	}
+	// 后继节点也熔断时，由本节点加载
+	p.peers.getters[next].getBreaker().failure()
+	if _, ok := p.PickPeer(key); ok {
+		t.Fatalf("expect local load when all peers are open")
+	}
+}
+
+func TestPeerTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	p := NewHTTPPool("http://a", &HTTPPoolOption{Timeout: 20 * time.Millisecond, FailureThreshold: 1, Cooldown: time.Minute})
+	getter := p.newGetter(server.URL)
+	start := time.Now()
+	if err := getter.Get(&pb.Request{Group: "g", Key: "k"}, &pb.Response{}); err == nil {
+		t.Fatalf("expect timeout error")
+	}
+	if d := time.Since(start); d > 150*time.Millisecond {
+		t.Fatalf("request should time out quickly, took %v", d)
+	}
+	if getter.breaker.allow() {
+		t.Fatalf("timeout should open the breaker")
+	}
+}
@@ -1,7 +1,33 @@
 package geecache
 
+import "time"
+
 type ByteView struct {
 	b []byte
+	// 过期时间，零值表示永不过期
+	e time.Time
+}
+
+// Expire 返回值的过期时间，零值表示永不过期
+func (v ByteView) Expire() time.Time {
+	return v.e
+}
+
+// newByteView 用其他节点传来的值和过期时间创建ByteView，expireNano为0表示永不过期
+func newByteView(b []byte, expireNano int64) ByteView {
+	bv := ByteView{b: b}
+	if expireNano != 0 {
+		bv.e = time.Unix(0, expireNano)
+	}
+	return bv
+}
+
+// expireNano 以Unix纳秒的形式返回过期时间，0表示永不过期，用于在节点之间传递
+func (v ByteView) expireNano() int64 {
+	if v.e.IsZero() {
+		return 0
+	}
+	return v.e.UnixNano()
 }
 
 func (v ByteView) Len() int {
@@ -9,9 +35,13 @@ func (v ByteView) Len() int {
 }
 
 func (v ByteView) ByteSlice() []byte {
-	slice := make([]byte, v.Len())
-	copy(slice, v.b)
-	return slice
+	return cloneBytes(v.b)
+}
+
+func cloneBytes(b []byte) []byte {
+	c := make([]byte, len(b))
+	copy(c, b)
+	return c
 }
 
 func (v ByteView) String() string {
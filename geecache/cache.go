@@ -2,34 +2,123 @@ package geecache
 
 import (
 	"sync"
+	"time"
 
+	"geektutu/geecache/arc"
+	"geektutu/geecache/lfu"
 	"geektutu/geecache/lru"
+	"geektutu/geecache/policy"
+	"geektutu/geecache/twoq"
 )
 
+// PolicyFunc 根据容量创建淘汰策略，用于GroupOption.Policy，记录被删除时应当调用onRemoved并说明原因
+type PolicyFunc func(maxBytes int64, onRemoved func(string, policy.Value, policy.Reason)) policy.Policy
+
+// 内置的淘汰策略
+func LRU(maxBytes int64, onRemoved func(string, policy.Value, policy.Reason)) policy.Policy {
+	c := lru.New(maxBytes, nil)
+	c.OnRemoved = onRemoved
+	return c
+}
+
+func LFU(maxBytes int64, onRemoved func(string, policy.Value, policy.Reason)) policy.Policy {
+	c := lfu.New(maxBytes, nil)
+	c.OnRemoved = onRemoved
+	return c
+}
+
+func ARC(maxBytes int64, onRemoved func(string, policy.Value, policy.Reason)) policy.Policy {
+	c := arc.New(maxBytes, nil)
+	c.OnRemoved = onRemoved
+	return c
+}
+
+func TwoQ(maxBytes int64, onRemoved func(string, policy.Value, policy.Reason)) policy.Policy {
+	c := twoq.New(maxBytes, nil)
+	c.OnRemoved = onRemoved
+	return c
+}
+
 type cache struct {
 	mu         sync.Mutex
-	lru        *lru.Cache
+	store      policy.Policy
+	newPolicy  PolicyFunc
 	cacheBytes int64
+	// 定期清理过期记录的间隔，为0时只在访问时清理
+	sweepInterval time.Duration
+	sweeping      bool
+	// 统计数据，由mu保护
+	nget, nhit               int64
+	nevict, nexpire, nremove int64
 }
 
 func (c *cache) add(key string, value ByteView) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	if c.lru == nil {
+	if c.store == nil {
 		// 什么时候初始化c.cacheBytes？
 		// 使用composite literal也是一种有效的初始化，并非一定要定义类型相关的New函数。
-		c.lru = lru.New(c.cacheBytes, nil)
+		if c.newPolicy == nil {
+			c.newPolicy = LRU
+		}
+		c.store = c.newPolicy(c.cacheBytes, func(_ string, _ policy.Value, reason policy.Reason) {
+			switch reason {
+			case policy.Evicted:
+				c.nevict++
+			case policy.Expired:
+				c.nexpire++
+			case policy.Removed:
+				c.nremove++
+			}
+		})
+	}
+	c.store.AddWithExpire(key, value, value.e)
+	// 第一次添加会过期的记录时，才启动后台的清理
+	if !value.e.IsZero() && !c.sweeping && c.sweepInterval > 0 {
+		c.sweeping = true
+		go c.sweep()
+	}
+}
+
+// sweep 定期删除过期的记录，group是全局的，因此清理会一直运行
+func (c *cache) sweep() {
+	ticker := time.NewTicker(c.sweepInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		c.mu.Lock()
+		c.store.RemoveExpired()
+		c.mu.Unlock()
 	}
-	c.lru.Add(key, value)
 }
 
 func (c *cache) get(key string) (ByteView, bool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	if c.lru != nil {
-		if v, ok := c.lru.Get(key); ok {
+	c.nget++
+	if c.store != nil {
+		if v, ok := c.store.Get(key); ok {
+			c.nhit++
 			return v.(ByteView), true
 		}
 	}
 	return ByteView{}, false
 }
+
+func (c *cache) remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.store != nil {
+		c.store.Remove(key)
+	}
+}
+
+func (c *cache) stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := CacheStats{Gets: c.nget, Hits: c.nhit, Evictions: c.nevict, Expirations: c.nexpire, Removals: c.nremove}
+	if c.store != nil {
+		s.Bytes = c.store.Bytes()
+		s.Items = int64(c.store.Len())
+	}
+	return s
+}
@@ -2,30 +2,62 @@ package consistenthash
 
 import (
 	"hash/crc32"
+	"math"
 	"sort"
 	"strconv"
 )
 
 type Hash func(data []byte) uint32
 
+// Locator 把key映射到节点，Map和Rendezvous都实现了它
+type Locator interface {
+	// Add 添加权重为1的节点
+	Add(nodes ...string)
+	// AddWeighted 添加节点，节点分到的key与weight成正比，重复添加时更新权重。
+	// weight小于等于0时按1处理
+	AddWeighted(node string, weight int)
+	Remove(nodes ...string)
+	// Get 返回key所在的节点，没有节点时返回""
+	Get(key string) string
+	// GetN 返回key的前n个不同的节点，第一个与Get相同，节点不足n个时返回所有节点
+	GetN(key string, n int) []string
+}
+
+var _ Locator = (*Map)(nil)
+var _ Locator = (*Rendezvous)(nil)
+
 // 抽象对象就是关注其属性和行为
 // 一致性hash这个对象的属性，包括虚拟扩充节点，其上的keys，以及hashmap将虚拟节点映射到物理节点上。
 // 其行为主要是添加物理节点，或者删除物理节点，或者根据key找到对应的物理节点。
-// replicas是虚拟节点倍数，用于减轻数据倾斜
+// replicas是虚拟节点倍数，用于减轻数据倾斜，权重为w的节点有w*replicas个虚拟节点
 // keys是所有节点的hash值，表示一个hash环
 // hashmap用于将多个虚拟keys映射为实际的节点名
+// weights记录每个物理节点的权重，loads记录有界负载时每个物理节点的负载
 type Map struct {
 	hash     Hash
 	replicas int
 	keys     []int
 	hashmap  map[int]string
+	weights  map[string]int
+	// 所有节点的权重之和
+	totalWeight int
+
+	loadFactor float64
+	loads      map[string]int64
+	totalLoad  int64
 }
 
+// DefaultLoadFactor 是有界负载的缺省系数，每个节点的负载不超过平均负载的1.25倍
+const DefaultLoadFactor = 1.25
+
 func New(replicas int, fn Hash) *Map {
 	m := &Map{
-		replicas: replicas,
-		hash:     fn,
-		hashmap:  make(map[int]string),
+		replicas:   replicas,
+		hash:       fn,
+		hashmap:    make(map[int]string),
+		weights:    make(map[string]int),
+		loadFactor: DefaultLoadFactor,
+		loads:      make(map[string]int64),
 	}
 
 	if m.hash == nil {
@@ -38,41 +70,71 @@ func New(replicas int, fn Hash) *Map {
 // 填充hash环keys,以及hashmap
 func (m *Map) Add(nodes ...string) {
 	for _, node := range nodes {
-		for i := 0; i < m.replicas; i++ {
-			vnode := strconv.Itoa(i) + node
-			vkey := int(m.hash([]byte(vnode)))
-			m.keys = append(m.keys, vkey)
-			// 将相关vkey映射为同一个物理节点
-			m.hashmap[vkey] = node
-		}
+		m.AddWeighted(node, 1)
+	}
+}
+
+// AddWeighted 添加权重为weight的节点，虚拟节点数与权重成正比
+func (m *Map) AddWeighted(node string, weight int) {
+	if weight <= 0 {
+		weight = 1
+	}
+	if _, ok := m.weights[node]; ok {
+		m.Remove(node)
+	}
+	m.weights[node] = weight
+	m.totalWeight += weight
+	for i := 0; i < m.replicas*weight; i++ {
+		vnode := strconv.Itoa(i) + node
+		vkey := int(m.hash([]byte(vnode)))
+		m.keys = append(m.keys, vkey)
+		// 将相关vkey映射为同一个物理节点
+		m.hashmap[vkey] = node
 	}
 	sort.Ints(m.keys)
 }
 
-/*
-func (m *Map) Delete(nodes ...string) {
+// Remove 删除物理节点和它的虚拟节点，其他节点上的key不受影响
+func (m *Map) Remove(nodes ...string) {
 	for _, node := range nodes {
-		for k, v := range m.hashmap {
-			if v == node {
-				delete(m.hashmap, k)
-				deleteSlice(m.keys, k)
+		weight, ok := m.weights[node]
+		if !ok {
+			continue
+		}
+		delete(m.weights, node)
+		m.totalWeight -= weight
+		m.totalLoad -= m.loads[node]
+		delete(m.loads, node)
+		for i := 0; i < m.replicas*weight; i++ {
+			vkey := int(m.hash([]byte(strconv.Itoa(i) + node)))
+			// 虚拟节点的hash冲突时，hashmap中保存的可能是其他节点
+			if m.hashmap[vkey] == node {
+				delete(m.hashmap, vkey)
 			}
 		}
 	}
+	// m.keys是有序的，过滤之后仍然有序
+	keys := m.keys[:0]
+	for _, k := range m.keys {
+		if _, ok := m.hashmap[k]; ok {
+			keys = append(keys, k)
+		}
+	}
+	m.keys = keys
 }
 
-// 注意s是有序的
-func deleteSlice(s []int, k int) {
-	p := sort.Search(len(s), func(i int) bool {
-		return s[i] >= k
-	})
-	s = append(s[:p], s[p+1:]...)
-} */
-
 // 根据键值（类型为key），将其映射到特定的物理节点
 func (m *Map) Get(key string) string {
+	if len(m.keys) == 0 {
+		return ""
+	}
 	// 我们首先计算key的hash值（string->int)，然后根据int得到hash环上的虚拟节点对应的key
 	// 然后根据这个虚拟key，以及m.hasmap得到对应的物理node
+	return m.hashmap[m.keys[m.search(key)]]
+}
+
+// search 返回key在hash环上顺时针方向的第一个虚拟节点的下标
+func (m *Map) search(key string) int {
 	// k为key对应到hash环上的的位置
 	k := int(m.hash([]byte(key)))
 
@@ -80,5 +142,80 @@ func (m *Map) Get(key string) string {
 		return m.keys[i] >= k
 	})
 	// 注意n有可能等于len(m.keys)，也就是说，k大于环上的所有keys。因此要注意取模！
-	return m.hashmap[m.keys[n%len(m.keys)]]
+	return n % len(m.keys)
+}
+
+// GetN 从key的位置沿hash环顺时针查找n个不同的物理节点
+func (m *Map) GetN(key string, n int) []string {
+	if len(m.keys) == 0 || n <= 0 {
+		return nil
+	}
+	if n > len(m.weights) {
+		n = len(m.weights)
+	}
+	nodes := make([]string, 0, n)
+	seen := make(map[string]bool, n)
+	for i, start := 0, m.search(key); i < len(m.keys) && len(nodes) < n; i++ {
+		node := m.hashmap[m.keys[(start+i)%len(m.keys)]]
+		if !seen[node] {
+			seen[node] = true
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// SetLoadFactor 设置有界负载的系数c，每个节点的负载不超过c倍的平均负载(按权重计算)，c应当大于1
+func (m *Map) SetLoadFactor(c float64) {
+	m.loadFactor = c
+}
+
+// GetLeast 实现有界负载的一致性hash(consistent hashing with bounded loads)：
+// 从key的位置沿hash环顺时针查找第一个负载未满的节点，而不总是返回Get的结果。
+// 调用方在开始处理时调用Inc，处理结束后调用Done，从而记录每个节点的负载
+func (m *Map) GetLeast(key string) string {
+	if len(m.keys) == 0 {
+		return ""
+	}
+	for i, start := 0, m.search(key); i < len(m.keys); i++ {
+		node := m.hashmap[m.keys[(start+i)%len(m.keys)]]
+		if m.loads[node] < m.capacity(node) {
+			return node
+		}
+	}
+	// 容量向上取整，不会所有节点都满，这里只是防御
+	return m.hashmap[m.keys[m.search(key)]]
+}
+
+// capacity 返回节点的负载上限：把包括新请求在内的总负载按权重平分，再乘以loadFactor
+func (m *Map) capacity(node string) int64 {
+	avg := float64(m.totalLoad+1) * float64(m.weights[node]) / float64(m.totalWeight)
+	return int64(math.Ceil(avg * m.loadFactor))
+}
+
+// Inc 增加节点的负载
+func (m *Map) Inc(node string) {
+	if _, ok := m.weights[node]; !ok {
+		return
+	}
+	m.loads[node]++
+	m.totalLoad++
+}
+
+// Done 减少节点的负载
+func (m *Map) Done(node string) {
+	if m.loads[node] <= 0 {
+		return
+	}
+	m.loads[node]--
+	m.totalLoad--
+}
+
+// Loads 返回每个节点当前的负载
+func (m *Map) Loads() map[string]int64 {
+	loads := make(map[string]int64, len(m.loads))
+	for node, load := range m.loads {
+		loads[node] = load
+	}
+	return loads
 }
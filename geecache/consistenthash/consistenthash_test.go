@@ -1,6 +1,7 @@
 package consistenthash
 
 import (
+	"math"
 	"strconv"
 	"testing"
 )
@@ -41,3 +42,140 @@ func TestHashing(t *testing.T) {
 	}
 
 }
+
+func TestEmpty(t *testing.T) {
+	for _, l := range []Locator{New(3, nil), NewRendezvous(nil)} {
+		if node := l.Get("key"); node != "" {
+			t.Fatalf("%T: empty locator should return \"\", got %s", l, node)
+		}
+		l.Add("a")
+		l.Remove("a")
+		if node := l.Get("key"); node != "" || len(l.GetN("key", 2)) != 0 {
+			t.Fatalf("%T: locator should be empty after remove, got %s", l, node)
+		}
+	}
+}
+
+// distribution 统计n个key在各个节点上的数量
+func distribution(l Locator, n int) map[string]int {
+	counts := make(map[string]int)
+	for i := 0; i < n; i++ {
+		counts[l.Get("key"+strconv.Itoa(i))]++
+	}
+	return counts
+}
+
+func TestDistribution(t *testing.T) {
+	nodes := []string{"10.0.0.1:8001", "10.0.0.2:8001", "10.0.0.3:8001", "10.0.0.4:8001", "10.0.0.5:8001"}
+	const keys = 50000
+	for _, l := range []Locator{New(200, nil), NewRendezvous(nil)} {
+		l.Add(nodes...)
+		counts := distribution(l, keys)
+		mean := float64(keys) / float64(len(nodes))
+		for _, node := range nodes {
+			if dev := math.Abs(float64(counts[node])-mean) / mean; dev > 0.2 {
+				t.Errorf("%T: node %s got %d keys, deviates %.2f from mean", l, node, counts[node], dev)
+			}
+		}
+
+		// 权重为3的节点分到的key约为其他节点的3倍
+		l.AddWeighted(nodes[0], 3)
+		counts = distribution(l, keys)
+		ratio := float64(counts[nodes[0]]) / float64(counts[nodes[1]])
+		if ratio < 2.2 || ratio > 3.8 {
+			t.Errorf("%T: weighted node got %.2f times keys, expect about 3", l, ratio)
+		}
+		l.AddWeighted(nodes[0], 1)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	for _, l := range []Locator{New(50, nil), NewRendezvous(nil)} {
+		l.Add("a", "b", "c", "d")
+		before := make(map[string]string)
+		for i := 0; i < 1000; i++ {
+			key := strconv.Itoa(i)
+			before[key] = l.Get(key)
+		}
+		l.Remove("b")
+		// 只有原来在b上的key会移动
+		for key, node := range before {
+			now := l.Get(key)
+			if now == "b" || (node != "b" && now != node) {
+				t.Fatalf("%T: key %s moved from %s to %s", l, key, node, now)
+			}
+		}
+	}
+}
+
+func TestGetN(t *testing.T) {
+	for _, l := range []Locator{New(50, nil), NewRendezvous(nil)} {
+		l.Add("a", "b", "c")
+		for i := 0; i < 100; i++ {
+			key := strconv.Itoa(i)
+			nodes := l.GetN(key, 2)
+			if len(nodes) != 2 || nodes[0] != l.Get(key) || nodes[0] == nodes[1] {
+				t.Fatalf("%T: unexpected GetN result %v", l, nodes)
+			}
+		}
+		if nodes := l.GetN("key", 5); len(nodes) != 3 {
+			t.Fatalf("%T: GetN should return all nodes, got %v", l, nodes)
+		}
+	}
+}
+
+func TestBoundedLoads(t *testing.T) {
+	m := New(50, nil)
+	m.Add("a", "b", "c", "d")
+	m.SetLoadFactor(1.25)
+	// 所有请求都是同一个热点key，负载仍然被分摊到各个节点
+	for i := 0; i < 100; i++ {
+		m.Inc(m.GetLeast("hot"))
+	}
+	loads := m.Loads()
+	for node, load := range loads {
+		if max := int64(math.Ceil(100.0 / 4 * 1.25)); load > max {
+			t.Fatalf("node %s load %d exceeds %d", node, load, max)
+		}
+	}
+	if len(loads) != 4 {
+		t.Fatalf("load should spread to all nodes, got %v", loads)
+	}
+	owner := m.Get("hot")
+	for i := 0; i < 100; i++ {
+		for node := range loads {
+			m.Done(node)
+		}
+	}
+	// 负载为0时与Get相同
+	if m.GetLeast("hot") != owner {
+		t.Fatalf("GetLeast should return owner when not loaded")
+	}
+}
+
+func TestNonPositiveWeight(t *testing.T) {
+	for _, l := range []Locator{New(50, nil), NewRendezvous(nil)} {
+		want := make(map[string]string)
+		l.Add("a", "b", "c")
+		for i := 0; i < 1000; i++ {
+			key := strconv.Itoa(i)
+			want[key] = l.Get(key)
+		}
+		// 小于等于0的权重按1处理
+		l.AddWeighted("a", 0)
+		l.AddWeighted("b", -2)
+		for key, node := range want {
+			if got := l.Get(key); got != node {
+				t.Fatalf("%T: key %s moved from %s to %s", l, key, node, got)
+			}
+		}
+		if nodes := l.GetN("1", 5); len(nodes) != 3 {
+			t.Fatalf("%T: expect 3 nodes, got %v", l, nodes)
+		}
+	}
+	m := New(50, nil)
+	m.AddWeighted("a", -1)
+	if m.totalWeight != 1 {
+		t.Fatalf("expect total weight 1, got %d", m.totalWeight)
+	}
+}
@@ -0,0 +1,103 @@
+package consistenthash
+
+import (
+	"hash/crc32"
+	"math"
+	"sort"
+)
+
+// Rendezvous 实现了最高随机权重(rendezvous/HRW)hash：对每个key，所有节点按hash(节点, key)打分，分数最高的节点胜出。
+// 与hash环相比，不需要虚拟节点也能均匀分布，删除节点时只有该节点上的key会移动，代价是Get需要遍历所有节点
+type Rendezvous struct {
+	hash  Hash
+	nodes []rendezvousNode
+}
+
+type rendezvousNode struct {
+	name   string
+	hash   uint32
+	weight float64
+}
+
+func NewRendezvous(fn Hash) *Rendezvous {
+	r := &Rendezvous{hash: fn}
+	if r.hash == nil {
+		r.hash = crc32.ChecksumIEEE
+	}
+	return r
+}
+
+func (r *Rendezvous) Add(nodes ...string) {
+	for _, node := range nodes {
+		r.AddWeighted(node, 1)
+	}
+}
+
+func (r *Rendezvous) AddWeighted(node string, weight int) {
+	if weight <= 0 {
+		weight = 1
+	}
+	r.Remove(node)
+	r.nodes = append(r.nodes, rendezvousNode{name: node, hash: r.hash([]byte(node)), weight: float64(weight)})
+}
+
+func (r *Rendezvous) Remove(nodes ...string) {
+	for _, node := range nodes {
+		for i := range r.nodes {
+			if r.nodes[i].name == node {
+				r.nodes = append(r.nodes[:i], r.nodes[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+func (r *Rendezvous) Get(key string) string {
+	k := r.hash([]byte(key))
+	best, bestScore := "", math.Inf(-1)
+	for _, node := range r.nodes {
+		if s := node.score(k); s > bestScore {
+			best, bestScore = node.name, s
+		}
+	}
+	return best
+}
+
+// GetN 返回分数最高的n个节点
+func (r *Rendezvous) GetN(key string, n int) []string {
+	if n <= 0 || len(r.nodes) == 0 {
+		return nil
+	}
+	k := r.hash([]byte(key))
+	scores := make(map[string]float64, len(r.nodes))
+	nodes := make([]string, len(r.nodes))
+	for i, node := range r.nodes {
+		nodes[i] = node.name
+		scores[node.name] = node.score(k)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return scores[nodes[i]] > scores[nodes[j]]
+	})
+	if n > len(nodes) {
+		n = len(nodes)
+	}
+	return nodes[:n]
+}
+
+// score 是加权的分数：把hash映射为(0,1)之间的均匀分布u，分数为-weight/ln(u)，
+// 这样每个节点胜出的概率与权重成正比
+func (n rendezvousNode) score(key uint32) float64 {
+	h := mix(uint64(n.hash)<<32 | uint64(key))
+	u := (float64(h>>11) + 0.5) / (1 << 53)
+	return -n.weight / math.Log(u)
+}
+
+// mix 是murmur3的64位finalizer，用于打散节点和key的hash组合
+func mix(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
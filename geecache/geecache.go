@@ -1,9 +1,13 @@
 package geecache
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"math/rand"
+	"strings"
 	"sync"
+	"time"
 
 	pb "geektutu/geecache/geecachepb"
 	"geektutu/geecache/singleflight"
@@ -26,6 +30,72 @@ func (gf GetterFunc) Get(key string) ([]byte, error) {
 	return gf(key)
 }
 
+// TTLGetter 是可以为每个值指定有效期的Getter，Group的getter实现了它时，使用GetWithTTL加载数据：
+// ttl大于0时值在ttl之后过期，等于0时使用GroupOption.DefaultTTL，小于0时永不过期
+type TTLGetter interface {
+	GetWithTTL(key string) ([]byte, time.Duration, error)
+}
+
+// TTLGetterFunc 同时实现了Getter和TTLGetter，可以直接传给NewGroup
+type TTLGetterFunc func(string) ([]byte, time.Duration, error)
+
+func (f TTLGetterFunc) Get(key string) ([]byte, error) {
+	bs, _, err := f(key)
+	return bs, err
+}
+
+func (f TTLGetterFunc) GetWithTTL(key string) ([]byte, time.Duration, error) {
+	return f(key)
+}
+
+// GroupOption 是Group的可选配置
+type GroupOption struct {
+	// DefaultTTL 加载的值缺省的有效期，为0表示永不过期
+	DefaultTTL time.Duration
+	// SweepInterval 后台清理过期值的间隔，为0时只在访问时清理
+	SweepInterval time.Duration
+	// Policy 缓存的淘汰策略，为nil时使用LRU
+	Policy PolicyFunc
+	// HotCacheRatio hotCache的容量占cacheBytes的比例，为0时使用默认值，小于0时不使用hotCache。
+	// cacheBytes为0（不限制大小）时也不使用hotCache
+	HotCacheRatio float64
+	// HotCacheProbability 从其他节点获取的值放入hotCache的概率，为0时使用默认值
+	HotCacheProbability float64
+	// BroadcastRemove 为true时Remove通知所有节点删除，而不只是key所在的节点，
+	// 这样其他节点hotCache中的副本也会被删除
+	BroadcastRemove bool
+	// FallbackLocal 为true时，从key所在的节点获取失败后改为从本地加载，否则直接返回错误
+	FallbackLocal bool
+}
+
+const (
+	defaultHotCacheRatio       = 1.0 / 8
+	defaultHotCacheProbability = 0.1
+)
+
+var DefaultGroupOption = GroupOption{
+	SweepInterval:       time.Minute,
+	HotCacheRatio:       defaultHotCacheRatio,
+	HotCacheProbability: defaultHotCacheProbability,
+}
+
+func parseGroupOption(opts ...*GroupOption) GroupOption {
+	if len(opts) > 1 {
+		panic("should specify at most 1 group option")
+	}
+	if len(opts) == 0 || opts[0] == nil {
+		return DefaultGroupOption
+	}
+	opt := *opts[0]
+	if opt.HotCacheRatio == 0 {
+		opt.HotCacheRatio = defaultHotCacheRatio
+	}
+	if opt.HotCacheProbability == 0 {
+		opt.HotCacheProbability = defaultHotCacheProbability
+	}
+	return opt
+}
+
 type Group struct {
 	name string
 	// 用户定制的回调函数，当缓冲不中时调用
@@ -37,11 +107,18 @@ type Group struct {
 	// 如果是对不同package中类型对象的引用，那么往往是指针，因为有可能先在别的地方定义，然后再这里引用
 	// 如果是同一个package，且只有自己会使用，那么就直接用对象而不是相应的指针。
 	mainCache cache
+	// hotCache 缓存从其他节点获取的热点数据，热点key不必每次都访问它所在的节点。
+	// 只有一部分从其他节点获取的值会放入hotCache，越热的key越可能被放入
+	hotCache cache
 	// 所谓的PeerPicker实质上就是一个HTTPPool，它实现了PeerPicker接口
 	// 我们可以HTTPPool视为一个远程缓存的查找对象。
 	peer PeerPicker
 
 	sc *singleflight.SingleCall
+
+	opt GroupOption
+
+	stats groupStats
 }
 
 var (
@@ -50,15 +127,29 @@ var (
 	groups = make(map[string]*Group)
 )
 
-func NewGroup(name string, cacheBytes int64, getter Getter) *Group {
+// NewGroup 创建一个Group，opts最多只能有一个，为空时使用DefaultGroupOption
+func NewGroup(name string, cacheBytes int64, getter Getter, opts ...*GroupOption) *Group {
 	if getter == nil {
 		panic("nil getter")
 	}
+	opt := parseGroupOption(opts...)
 	g := &Group{
 		name:      name,
 		getter:    getter,
-		mainCache: cache{cacheBytes: cacheBytes},
+		mainCache: cache{cacheBytes: cacheBytes, sweepInterval: opt.SweepInterval, newPolicy: opt.Policy},
 		sc:        &singleflight.SingleCall{},
+		opt:       opt,
+	}
+	// hotCache的容量在cacheBytes之外。容量为0表示不限制大小，hotCache会留下从其他节点获取的所有值，
+	// 因此cacheBytes为0或者按比例算出的容量为0时不使用hotCache
+	if hotBytes := int64(float64(cacheBytes) * opt.HotCacheRatio); hotBytes > 0 {
+		g.hotCache = cache{
+			cacheBytes:    hotBytes,
+			sweepInterval: opt.SweepInterval,
+			newPolicy:     opt.Policy,
+		}
+	} else {
+		g.opt.HotCacheRatio = -1
 	}
 	mu.Lock()
 	defer mu.Unlock()
@@ -77,53 +168,106 @@ func GetGroup(name string) *Group {
 // 一直永远get，如果不存在（未命中），则从源获取数据，
 // 如果Getter获取失败，则返回这个失败信息。
 func (g *Group) Get(key string) (ByteView, error) {
+	return g.get(key, true)
+}
+
+// serve 处理其他节点的请求：收到请求的节点就是key所在的节点，或者在所在节点熔断时暂时代替它，
+// 因此未命中时从本地加载，不再转发给其他节点
+func (g *Group) serve(key string) (ByteView, error) {
+	return g.get(key, false)
+}
+
+func (g *Group) get(key string, usePeer bool) (ByteView, error) {
 	// 为什么要对空key进行特别处理呢？因为我们不允许key为空串！
 	// 对于其他任意串，如果存在（不论在不在缓存中）则返回，否则0值和err
 	if key == "" {
 		return ByteView{}, fmt.Errorf("key is required")
 	}
+	g.stats.Gets.Add(1)
 	if bv, ok := g.mainCache.get(key); ok {
 		log.Printf("[GeeCache] key %s hit in cache \n", key)
+		g.stats.CacheHits.Add(1)
+		return bv, nil
+	}
+	if bv, ok := g.hotCache.get(key); ok {
+		log.Printf("[GeeCache] key %s hit in hot cache \n", key)
+		g.stats.CacheHits.Add(1)
 		return bv, nil
 	}
 	// 我们之所以要定义load函数，是因为这个函数的语义复杂度等同于上面的get层面（从缓存中获取）
 	// load表示：
 	// 从本地源获取，然后构建对象，并将其加载到缓存中，最后返回这个对象。
 	log.Printf("[GeeCache] key %s miss in cache\n", key)
-	return g.load(key)
+	return g.load(key, usePeer)
 }
 
-func (g *Group) load(key string) (ByteView, error) {
-	if g.peer != nil {
-		if peer, ok := g.peer.PickPeer(key); ok {
-			val, err := g.sc.Do(key, func() (interface{}, error) {
-				log.Printf("we try to get from remote server %s\n", (g.peer).(*HTTPPool).peers.Get(key))
+func (g *Group) load(key string, usePeer bool) (ByteView, error) {
+	// 并发访问同一个key时只加载一次，其他调用共享加载的结果
+	called := false
+	val, err := g.sc.Do(key, func() (interface{}, error) {
+		called = true
+		if g.peer != nil && usePeer {
+			if peer, ok := g.peer.PickPeer(key); ok {
+				log.Printf("we try to get %s from remote peer\n", key)
 				value, err := g.getFromPeer(peer, key)
-				if err != nil {
+				if err == nil {
+					g.stats.PeerLoads.Add(1)
+					g.populateHotCache(key, value)
+					return value, nil
+				}
+				g.stats.PeerErrors.Add(1)
+				if !g.opt.FallbackLocal {
 					return value, err
 				}
-				return value, nil
-			})
-			return val.(ByteView), err
+				log.Printf("[GeeCache] failed to get %s from peer, fall back to local: %v\n", key, err)
+			}
 		}
+		log.Printf("we try to get from local\n")
+		value, err := g.getLocally(key)
+		if err != nil {
+			g.stats.LocalLoadErrs.Add(1)
+			return value, err
+		}
+		g.stats.LocalLoads.Add(1)
+		return value, nil
+	})
+	if !called {
+		g.stats.LoadsDeduped.Add(1)
 	}
-	log.Printf("we try to get from local\n")
-	return g.getLocally(key)
+	return val.(ByteView), err
 }
 
 func (g *Group) getLocally(key string) (ByteView, error) {
 	// Getter.Get会从本地返回一个[]byte对象，这个对象之前不存在，现在加载到了内存中
 	// 因此后面可以直接使用这个对象，而不必再拷贝了。
-	bs, err := g.getter.Get(key)
+	var bs []byte
+	var ttl time.Duration
+	var err error
+	if tg, ok := g.getter.(TTLGetter); ok {
+		bs, ttl, err = tg.GetWithTTL(key)
+	} else {
+		bs, err = g.getter.Get(key)
+	}
 	if err != nil {
 		// 返回0值和error对象
 		return ByteView{}, err
 	}
-	bv := ByteView{bs}
+	bv := ByteView{b: bs, e: g.expireAt(ttl)}
 	g.mainCache.add(key, bv)
 	return bv, nil
 }
 
+// expireAt 返回有效期为ttl的值的过期时间，ttl的含义与TTLGetter相同
+func (g *Group) expireAt(ttl time.Duration) time.Time {
+	if ttl == 0 {
+		ttl = g.opt.DefaultTTL
+	}
+	if ttl > 0 {
+		return time.Now().Add(ttl)
+	}
+	return time.Time{}
+}
+
 func (g *Group) getFromPeer(peer PeerGetter, key string) (ByteView, error) {
 	request := &pb.Request{
 		Group: g.name,
@@ -137,7 +281,112 @@ func (g *Group) getFromPeer(peer PeerGetter, key string) (ByteView, error) {
 		return ByteView{}, err
 	}
 	//return ByteView{bytes}, nil
-	return ByteView{response.Value}, nil
+	// 使用数据所在节点的过期时间，而不是本地的DefaultTTL
+	return newByteView(response.Value, response.Expire), nil
+}
+
+// Set 把在其他地方计算好的值写入缓存，ttl的含义与TTLGetter相同。
+// key属于其他节点时写入该节点，并删除本地hotCache和mainCache中的旧副本（FallbackLocal时会写入mainCache）；属于本节点时直接写入mainCache
+func (g *Group) Set(key string, value []byte, ttl time.Duration) error {
+	if key == "" {
+		return fmt.Errorf("key is required")
+	}
+	bv := ByteView{b: cloneBytes(value), e: g.expireAt(ttl)}
+	if g.peer != nil {
+		peer, ok, err := g.ownerPeer(key)
+		if err != nil {
+			return err
+		}
+		if ok {
+			setter, ok := peer.(PeerSetter)
+			if !ok {
+				return fmt.Errorf("peer %T does not support set", peer)
+			}
+			g.hotCache.remove(key)
+			g.mainCache.remove(key)
+			req := &pb.SetRequest{Group: g.name, Key: key, Value: bv.b, Expire: bv.expireNano()}
+			return setter.Set(req, &pb.SetResponse{})
+		}
+	}
+	g.mainCache.add(key, bv)
+	return nil
+}
+
+// Remove 在数据源中的值更新后删除key：先删除本地的缓存，再通知key所在的节点删除。
+// 只有实现了PeerRemover的节点才能被通知，否则返回错误
+func (g *Group) Remove(ctx context.Context, key string) error {
+	if key == "" {
+		return fmt.Errorf("key is required")
+	}
+	g.removeLocally(key)
+	if g.peer == nil {
+		return nil
+	}
+	var peers []PeerGetter
+	if lister, ok := g.peer.(PeerLister); ok && g.opt.BroadcastRemove {
+		peers = lister.Peers()
+	} else {
+		peer, ok, err := g.ownerPeer(key)
+		if err != nil {
+			return fmt.Errorf("geecache: failed to remove %s: %v", key, err)
+		}
+		if ok {
+			peers = []PeerGetter{peer}
+		}
+	}
+
+	req := &pb.RemoveRequest{Group: g.name, Key: key}
+	errs := make([]error, len(peers))
+	var wg sync.WaitGroup
+	for i, peer := range peers {
+		remover, ok := peer.(PeerRemover)
+		if !ok {
+			errs[i] = fmt.Errorf("peer %T does not support remove", peer)
+			continue
+		}
+		wg.Add(1)
+		go func(i int, remover PeerRemover) {
+			defer wg.Done()
+			errs[i] = remover.Remove(ctx, req)
+		}(i, remover)
+	}
+	wg.Wait()
+
+	var failed []string
+	for _, err := range errs {
+		if err != nil {
+			failed = append(failed, err.Error())
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("geecache: failed to remove %s from %d of %d peers: %s",
+			key, len(failed), len(peers), strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// ownerPeer 返回key所属的节点，g.peer没有实现PeerOwner时使用PickPeer
+func (g *Group) ownerPeer(key string) (PeerGetter, bool, error) {
+	if owner, ok := g.peer.(PeerOwner); ok {
+		return owner.OwnerPeer(key)
+	}
+	peer, ok := g.peer.PickPeer(key)
+	return peer, ok, nil
+}
+
+// removeLocally 只删除本节点mainCache和hotCache中的key
+func (g *Group) removeLocally(key string) {
+	g.mainCache.remove(key)
+	g.hotCache.remove(key)
+}
+
+// populateHotCache 以HotCacheProbability的概率把从其他节点获取的值放入hotCache，
+// 访问越频繁的key越早被放入，只访问几次的key则不会占用hotCache
+func (g *Group) populateHotCache(key string, value ByteView) {
+	if g.opt.HotCacheRatio <= 0 || rand.Float64() >= g.opt.HotCacheProbability {
+		return
+	}
+	g.hotCache.add(key, value)
 }
 
 func (g *Group) RegisterPeerPicker(p PeerPicker) {
@@ -1,10 +1,16 @@
 package geecache
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"net/http/httptest"
 	"reflect"
 	"testing"
+	"time"
+
+	pb "geektutu/geecache/geecachepb"
+	"geektutu/geecache/lfu"
 )
 
 func anonymousFunc(key string) ([]byte, error) {
@@ -50,3 +56,246 @@ func TestGet(t *testing.T) {
 		log.Printf("we should not get 'unknown' and what we get is 0: %v\n", view)
 	}
 }
+
+func TestGetWithTTL(t *testing.T) {
+	loads := 0
+	gee := NewGroup("ttl", 2<<10, TTLGetterFunc(
+		func(key string) ([]byte, time.Duration, error) {
+			loads++
+			if key == "forever" {
+				return []byte(key), -1, nil
+			}
+			return []byte(key), 0, nil
+		}), &GroupOption{DefaultTTL: 50 * time.Millisecond})
+	for i := 0; i < 2; i++ {
+		if _, err := gee.Get("short"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := gee.Get("forever"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if loads != 2 {
+		t.Fatalf("expect 2 loads, got %d", loads)
+	}
+	v, _ := gee.Get("short")
+	if v.Expire().IsZero() {
+		t.Fatal("short should use the default ttl")
+	}
+	time.Sleep(60 * time.Millisecond)
+	gee.Get("short")
+	gee.Get("forever")
+	if loads != 3 {
+		t.Fatalf("expired value should be loaded again, loads = %d", loads)
+	}
+}
+
+func TestGroupPolicy(t *testing.T) {
+	gee := NewGroup("lfu", 2<<10, GetterFunc(anonymousFunc), &GroupOption{Policy: LFU})
+	if _, err := gee.Get("Tom"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := gee.mainCache.store.(*lfu.Cache); !ok {
+		t.Fatalf("expect lfu policy, got %T", gee.mainCache.store)
+	}
+}
+
+// fakePeer 把所有key都交给自己，并记录被访问的次数
+type fakePeer struct {
+	calls   int
+	removed []string
+	set     []*pb.SetRequest
+	// Peers返回的节点，为空时不广播
+	peers []PeerGetter
+}
+
+func (p *fakePeer) Remove(ctx context.Context, req *pb.RemoveRequest) error {
+	p.removed = append(p.removed, req.Key)
+	return nil
+}
+
+func (p *fakePeer) PickPeer(key string) (PeerGetter, bool) {
+	return p, true
+}
+
+func (p *fakePeer) Get(req *pb.Request, resp *pb.Response) error {
+	p.calls++
+	resp.Value = []byte("remote " + req.Key)
+	return nil
+}
+
+func (p *fakePeer) Set(req *pb.SetRequest, resp *pb.SetResponse) error {
+	p.set = append(p.set, req)
+	return nil
+}
+
+// fakeLister 是可以广播的fakePeer
+type fakeLister struct {
+	*fakePeer
+}
+
+func (p fakeLister) Peers() []PeerGetter {
+	return p.peers
+}
+
+func TestHotCache(t *testing.T) {
+	peer := &fakePeer{}
+	gee := NewGroup("hot", 2<<10, GetterFunc(anonymousFunc), &GroupOption{HotCacheProbability: 1})
+	gee.RegisterPeerPicker(peer)
+	for i := 0; i < 3; i++ {
+		if v, err := gee.Get("Tom"); err != nil || v.String() != "remote Tom" {
+			t.Fatalf("failed to get Tom from peer: %v %v", v, err)
+		}
+	}
+	if peer.calls != 1 {
+		t.Fatalf("hot key should be served from hot cache, peer called %d times", peer.calls)
+	}
+	if _, ok := gee.mainCache.get("Tom"); ok {
+		t.Fatalf("peer value should not be added to main cache")
+	}
+
+	peer = &fakePeer{}
+	cold := NewGroup("cold", 2<<10, GetterFunc(anonymousFunc), &GroupOption{HotCacheRatio: -1})
+	cold.RegisterPeerPicker(peer)
+	cold.Get("Tom")
+	cold.Get("Tom")
+	if peer.calls != 2 {
+		t.Fatalf("hot cache disabled, peer should be called twice, got %d", peer.calls)
+	}
+
+	// mainCache不限制大小时，hotCache没有容量上限，因此不使用
+	peer = &fakePeer{}
+	unbounded := NewGroup("unbounded", 0, GetterFunc(anonymousFunc), &GroupOption{HotCacheProbability: 1})
+	unbounded.RegisterPeerPicker(peer)
+	unbounded.Get("Tom")
+	unbounded.Get("Tom")
+	if peer.calls != 2 || unbounded.hotCache.stats().Items != 0 {
+		t.Fatalf("hot cache should be disabled without a size limit, peer called %d times", peer.calls)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	peer := &fakePeer{}
+	gee := NewGroup("remove", 2<<10, GetterFunc(anonymousFunc), &GroupOption{HotCacheProbability: 1})
+	gee.RegisterPeerPicker(peer)
+	gee.Get("Tom")
+	if err := gee.Remove(context.Background(), "Tom"); err != nil {
+		t.Fatal(err)
+	}
+	// hotCache中的副本被删除，删除请求转发给了key所在的节点
+	if _, ok := gee.hotCache.get("Tom"); ok {
+		t.Fatalf("Tom should be removed from hot cache")
+	}
+	if len(peer.removed) != 1 || peer.removed[0] != "Tom" {
+		t.Fatalf("remove should be forwarded to owner, got %v", peer.removed)
+	}
+
+	other := &fakePeer{}
+	owner := &fakePeer{}
+	owner.peers = []PeerGetter{owner, other}
+	broadcast := NewGroup("broadcast", 2<<10, GetterFunc(anonymousFunc), &GroupOption{BroadcastRemove: true})
+	broadcast.RegisterPeerPicker(fakeLister{owner})
+	if err := broadcast.Remove(context.Background(), "Tom"); err != nil {
+		t.Fatal(err)
+	}
+	if len(owner.removed) != 1 || len(other.removed) != 1 {
+		t.Fatalf("remove should be broadcast to all peers, got %v %v", owner.removed, other.removed)
+	}
+}
+
+func TestHTTPRemove(t *testing.T) {
+	gee := NewGroup("http-remove", 2<<10, GetterFunc(anonymousFunc))
+	gee.Get("Tom")
+	server := httptest.NewServer(NewHTTPPool("self"))
+	defer server.Close()
+
+	getter := &httpGetter{baseURL: server.URL + defaultBasePath}
+	if err := getter.Remove(context.Background(), &pb.RemoveRequest{Group: "http-remove", Key: "Tom"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := gee.mainCache.get("Tom"); ok {
+		t.Fatalf("Tom should be removed by DELETE request")
+	}
+	err := getter.Remove(context.Background(), &pb.RemoveRequest{Group: "unknown", Key: "Tom"})
+	if err == nil {
+		t.Fatalf("remove from unknown group should fail")
+	}
+}
+
+func TestSet(t *testing.T) {
+	loads := 0
+	gee := NewGroup("set", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		loads++
+		return []byte(key), nil
+	}))
+	if err := gee.Set("Tom", []byte("630"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := gee.Get("Tom"); err != nil || v.String() != "630" || v.Expire().IsZero() || loads != 0 {
+		t.Fatalf("failed to get value set locally: %v %v", v, err)
+	}
+
+	peer := &fakePeer{}
+	remote := NewGroup("set-remote", 2<<10, GetterFunc(anonymousFunc), &GroupOption{HotCacheProbability: 1})
+	remote.RegisterPeerPicker(peer)
+	remote.Get("Tom")
+	// 模拟FallbackLocal时留在mainCache中的副本
+	remote.mainCache.add("Tom", ByteView{b: []byte("589")})
+	if err := remote.Set("Tom", []byte("630"), -1); err != nil {
+		t.Fatal(err)
+	}
+	if len(peer.set) != 1 || string(peer.set[0].Value) != "630" || peer.set[0].Expire != 0 {
+		t.Fatalf("set should be forwarded to owner, got %v", peer.set)
+	}
+	if _, ok := remote.hotCache.get("Tom"); ok {
+		t.Fatalf("stale hot cache copy should be removed")
+	}
+	if _, ok := remote.mainCache.get("Tom"); ok {
+		t.Fatalf("stale main cache copy should be removed")
+	}
+}
+
+func TestHTTPSet(t *testing.T) {
+	gee := NewGroup("http-set", 2<<10, GetterFunc(anonymousFunc))
+	server := httptest.NewServer(NewHTTPPool("self"))
+	defer server.Close()
+
+	getter := &httpGetter{baseURL: server.URL + defaultBasePath}
+	expire := time.Now().Add(time.Minute)
+	req := &pb.SetRequest{Group: "http-set", Key: "Tom", Value: []byte("630"), Expire: expire.UnixNano()}
+	if err := getter.Set(req, &pb.SetResponse{}); err != nil {
+		t.Fatal(err)
+	}
+	v, ok := gee.mainCache.get("Tom")
+	if !ok || v.String() != "630" || !v.Expire().Equal(time.Unix(0, expire.UnixNano())) {
+		t.Fatalf("failed to set by PUT request: %v %v", v, v.Expire())
+	}
+}
+
+// failingPeer 是总是失败的节点
+type failingPeer struct{}
+
+func (failingPeer) PickPeer(key string) (PeerGetter, bool) {
+	return failingPeer{}, true
+}
+
+func (failingPeer) Get(req *pb.Request, resp *pb.Response) error {
+	return fmt.Errorf("peer is down")
+}
+
+func TestFallbackLocal(t *testing.T) {
+	gee := NewGroup("no-fallback", 2<<10, GetterFunc(anonymousFunc))
+	gee.RegisterPeerPicker(failingPeer{})
+	if _, err := gee.Get("Tom"); err == nil {
+		t.Fatalf("expect peer error without fallback")
+	}
+
+	fallback := NewGroup("fallback", 2<<10, GetterFunc(anonymousFunc), &GroupOption{FallbackLocal: true})
+	fallback.RegisterPeerPicker(failingPeer{})
+	if v, err := fallback.Get("Tom"); err != nil || v.String() != "Tom" {
+		t.Fatalf("expect local value after peer failure, got %v %v", v, err)
+	}
+	if s := fallback.Stats(); s.PeerErrors != 1 || s.LocalLoads != 1 {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+}
@@ -1,13 +1,15 @@
 package geecache
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
 	"net/url"
 	"strings"
-	"sync"
+	"time"
 
 	"geektutu/geecache/consistenthash"
 
@@ -26,19 +28,47 @@ type HTTPPool struct {
 	self     string
 	basePath string
 
-	mu sync.Mutex
-	// peers记录所有的服务节点，这是所有HTTPPool共享的基础数据，因此用指针。
-	peers *consistenthash.Map
 	// 每个物理节点，都有可能访问其他所有物理节点，因此根据节点名称建立好映射。
 	// 每个节点名称使用http://ip:port表示
-	httpGetters map[string]*httpGetter
+	peers *peerSet
+
+	opt HTTPPoolOption
+}
+
+// HTTPPoolOption 是HTTPPool访问其他节点时的可选配置
+type HTTPPoolOption struct {
+	// Timeout 每个访问其他节点的请求的超时时间，为0表示不超时
+	Timeout time.Duration
+	// FailureThreshold 节点连续失败多少次后熔断，小于等于0表示不熔断
+	FailureThreshold int
+	// Cooldown 节点熔断的时间，之后重新尝试访问
+	Cooldown time.Duration
+	// Locator 创建把key映射到节点的Locator，为nil时使用defaultReplicas倍虚拟节点的consistenthash.Map
+	Locator func() consistenthash.Locator
+}
+
+var DefaultHTTPPoolOption = HTTPPoolOption{
+	Timeout:          3 * time.Second,
+	FailureThreshold: 5,
+	Cooldown:         10 * time.Second,
 }
 
-func NewHTTPPool(self string) *HTTPPool {
-	return &HTTPPool{
+// NewHTTPPool 创建一个HTTPPool，opts最多只能有一个，为空时使用DefaultHTTPPoolOption
+func NewHTTPPool(self string, opts ...*HTTPPoolOption) *HTTPPool {
+	if len(opts) > 1 {
+		panic("should specify at most 1 http pool option")
+	}
+	opt := DefaultHTTPPoolOption
+	if len(opts) == 1 && opts[0] != nil {
+		opt = *opts[0]
+	}
+	p := &HTTPPool{
 		self:     self,
 		basePath: defaultBasePath,
+		opt:      opt,
 	}
+	p.peers = newPeerSet(self, opt.Locator, func(peer string) breakerGetter { return p.newGetter(peer) }, p.Log)
+	return p
 }
 
 func (p *HTTPPool) Log(format string, data ...interface{}) {
@@ -51,6 +81,11 @@ func (p *HTTPPool) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 	p.Log("%s %s", r.Method, r.URL.Path)
 
+	if r.URL.Path[len(p.basePath):] == statsPath {
+		p.serveStats(w, r)
+		return
+	}
+
 	// 默认请求path为defaultBasePath/<group>/<key>
 	parts := strings.SplitN(r.URL.Path[len(p.basePath):], "/", 2)
 	if len(parts) != 2 {
@@ -65,13 +100,25 @@ func (p *HTTPPool) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "invalid group", http.StatusNotFound)
 		return
 	}
-	bv, err := group.Get(key)
+	group.stats.ServerRequests.Add(1)
+	switch r.Method {
+	case http.MethodDelete:
+		// 其他节点转发的删除请求，只删除本地的缓存
+		group.removeLocally(key)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	case http.MethodPut:
+		// 其他节点转发的写入请求，直接写入本地的mainCache
+		p.serveSet(w, r, group, key)
+		return
+	}
+	bv, err := group.serve(key)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusNoContent)
 		return
 	}
 
-	body, err := proto.Marshal(&pb.Response{Value: bv.ByteSlice()})
+	body, err := proto.Marshal(&pb.Response{Value: bv.ByteSlice(), Expire: bv.expireNano()})
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
@@ -82,44 +129,82 @@ func (p *HTTPPool) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	w.Write(body)
 }
 
+func (p *HTTPPool) serveSet(w http.ResponseWriter, r *http.Request, group *Group, key string) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req := &pb.SetRequest{}
+	if err = proto.Unmarshal(body, req); err != nil {
+		http.Error(w, "decoding request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	group.mainCache.add(key, newByteView(req.Value, req.Expire))
+	w.WriteHeader(http.StatusNoContent)
+}
+
 // 初始化一致性hash环。参数peer为物理节点的url地址，比如http://IP:port
+// 已有节点的httpGetter和熔断状态会被保留
 func (p *HTTPPool) Set(peers ...string) {
-	p.mu.Lock()
-	defer p.mu.Unlock()
-	// 初始化一致性hash环
-	p.peers = consistenthash.New(defaultReplicas, nil)
-	// 向其中添加物理节点名称
-	p.peers.Add(peers...)
-
-	// 然后初始化p.httpGetters
-	p.httpGetters = make(map[string]*httpGetter, len(peers))
-	for _, peer := range peers {
-		// p.basePath默认为/_geecache/
-		p.httpGetters[peer] = &httpGetter{baseURL: peer + p.basePath}
-	}
+	p.peers.set(peers...)
 }
 
 // 根据key找到适当的peerGetter。就是key->
 func (p *HTTPPool) PickPeer(key string) (PeerGetter, bool) {
-	p.mu.Lock()
-	defer p.mu.Unlock()
+	return p.peers.pick(key)
+}
+
+// OwnerPeer 返回key所属的节点，所属节点熔断时返回错误，而不是交给后继节点
+func (p *HTTPPool) OwnerPeer(key string) (PeerGetter, bool, error) {
+	return p.peers.owner(key)
+}
 
-	peer := p.peers.Get(key)
-	if peer != p.self {
-		p.Log("Pick peer %s", peer)
-		return p.httpGetters[peer], true
+func (p *HTTPPool) newGetter(peer string) *httpGetter {
+	return &httpGetter{
+		baseURL: peer + p.basePath,
+		client:  &http.Client{Timeout: p.opt.Timeout},
+		breaker: newBreaker(p.opt.FailureThreshold, p.opt.Cooldown),
 	}
-	// 如果是自己，则返回nil, false
-	return nil, false
+}
+
+// Peers 返回除自己之外的所有节点
+func (p *HTTPPool) Peers() []PeerGetter {
+	return p.peers.others()
 }
 
 var _ PeerPicker = (*HTTPPool)(nil)
+var _ PeerOwner = (*HTTPPool)(nil)
+var _ PeerLister = (*HTTPPool)(nil)
 
 // 用于从其他物理节点获取数据，这个被隐藏在物理节点中，物理节点对外就是HTTPPool的ServeHTTP。
 //　为什么我们将其命名为HTTPPool呢？就是因为这个缓存是以HTTP Pool的形式提供的：
 // 每个服务节点代表整个pool对外提供数据缓存服务。
 type httpGetter struct {
 	baseURL string
+	// client带有超时时间，为nil时使用http.DefaultClient
+	client *http.Client
+	// breaker为nil表示不熔断
+	breaker *breaker
+}
+
+// do 发送请求，并根据结果更新熔断器：网络错误和5xx视为节点故障，其他状态码说明节点是正常的
+func (h *httpGetter) do(r *http.Request) (*http.Response, error) {
+	client := h.client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(r)
+	if err != nil || res.StatusCode >= http.StatusInternalServerError {
+		h.breaker.failure()
+	} else {
+		h.breaker.success()
+	}
+	return res, err
+}
+
+func (h *httpGetter) getBreaker() *breaker {
+	return h.breaker
 }
 
 /*func (h *httpGetter) Get(group string, key string) ([]byte, error) {
@@ -153,8 +238,11 @@ func (h *httpGetter) Get(req *pb.Request, resp *pb.Response) error {
 		url.PathEscape(req.Group),
 		url.PathEscape(req.Key),
 	)
-	res, err := http.Get(u)
-
+	r, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	res, err := h.do(r)
 	if err != nil {
 		return err
 	}
@@ -173,4 +261,57 @@ func (h *httpGetter) Get(req *pb.Request, resp *pb.Response) error {
 	return nil
 }
 
+func (h *httpGetter) Remove(ctx context.Context, req *pb.RemoveRequest) error {
+	u := fmt.Sprintf(
+		"%v%v/%v",
+		h.baseURL,
+		url.PathEscape(req.Group),
+		url.PathEscape(req.Key),
+	)
+	r, err := http.NewRequestWithContext(ctx, http.MethodDelete, u, nil)
+	if err != nil {
+		return err
+	}
+	res, err := h.do(r)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusNoContent && res.StatusCode != http.StatusOK {
+		return fmt.Errorf("server returned: %v", res.Status)
+	}
+	return nil
+}
+
+func (h *httpGetter) Set(req *pb.SetRequest, resp *pb.SetResponse) error {
+	u := fmt.Sprintf(
+		"%v%v/%v",
+		h.baseURL,
+		url.PathEscape(req.Group),
+		url.PathEscape(req.Key),
+	)
+	body, err := proto.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("encoding request body: %v", err)
+	}
+	r, err := http.NewRequest(http.MethodPut, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Content-Type", "application/octet-stream")
+	res, err := h.do(r)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusNoContent && res.StatusCode != http.StatusOK {
+		return fmt.Errorf("server returned: %v", res.Status)
+	}
+	return nil
+}
+
 var _ PeerGetter = (*httpGetter)(nil)
+var _ PeerRemover = (*httpGetter)(nil)
+var _ PeerSetter = (*httpGetter)(nil)
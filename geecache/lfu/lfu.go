@@ -0,0 +1,161 @@
+// Package lfu 实现了按访问频率淘汰的缓存，频率相同时淘汰最久未访问的记录。
+// 每个频率对应一个链表，Get和Add都是O(1)的，只有最低频率的链表被清空时才需要重新查找最小频率
+package lfu
+
+import (
+	"container/list"
+	"time"
+
+	"geektutu/geecache/policy"
+)
+
+type Cache struct {
+	maxBytes int64
+	nBytes   int64
+	cache    map[string]*list.Element
+	// 频率到记录链表的映射，链表头部是最近访问的记录
+	freqs     map[int]*list.List
+	minFreq   int
+	OnEvicted func(key string, value policy.Value)                       // 只在超出容量淘汰记录时调用
+	OnRemoved func(key string, value policy.Value, reason policy.Reason) // 记录因为任何原因被删除时调用，reason说明原因
+}
+
+var _ policy.Policy = (*Cache)(nil)
+
+type entry struct {
+	policy.Entry
+	freq int
+}
+
+// New 创建一个Cache，maxBytes为0表示不限制大小
+func New(maxBytes int64, onEvicted func(string, policy.Value)) *Cache {
+	return &Cache{
+		maxBytes:  maxBytes,
+		cache:     make(map[string]*list.Element),
+		freqs:     make(map[int]*list.List),
+		OnEvicted: onEvicted,
+	}
+}
+
+func (c *Cache) Get(key string) (policy.Value, bool) {
+	ele, ok := c.cache[key]
+	if !ok {
+		return nil, false
+	}
+	e := ele.Value.(*entry)
+	if e.Expired(time.Now()) {
+		c.removeElement(ele, policy.Expired)
+		return nil, false
+	}
+	c.touch(ele)
+	return e.Value, true
+}
+
+func (c *Cache) Add(key string, value policy.Value) {
+	c.AddWithExpire(key, value, time.Time{})
+}
+
+func (c *Cache) AddWithExpire(key string, value policy.Value, expire time.Time) {
+	if ele, ok := c.cache[key]; ok {
+		e := ele.Value.(*entry)
+		c.nBytes += int64(value.Len()) - int64(e.Value.Len())
+		e.Value, e.Expire = value, expire
+		c.touch(ele)
+	} else {
+		e := &entry{Entry: policy.Entry{Key: key, Value: value, Expire: expire}, freq: 1}
+		// 先淘汰再插入，否则新记录的频率最低，会被立即淘汰
+		for c.maxBytes != 0 && c.nBytes+e.Size() > c.maxBytes && len(c.cache) > 0 {
+			c.RemoveLeastFrequent()
+		}
+		c.cache[key] = c.list(1).PushFront(e)
+		c.minFreq = 1
+		c.nBytes += e.Size()
+	}
+	for c.maxBytes != 0 && c.nBytes > c.maxBytes && len(c.cache) > 0 {
+		c.RemoveLeastFrequent()
+	}
+}
+
+// RemoveLeastFrequent 淘汰访问频率最低的记录
+func (c *Cache) RemoveLeastFrequent() {
+	if l := c.freqs[c.minFreq]; l != nil {
+		c.removeElement(l.Back(), policy.Evicted)
+	}
+}
+
+func (c *Cache) Remove(key string) bool {
+	if ele, ok := c.cache[key]; ok {
+		c.removeElement(ele, policy.Removed)
+		return true
+	}
+	return false
+}
+
+func (c *Cache) RemoveExpired() int {
+	now := time.Now()
+	var expired []*list.Element
+	for _, ele := range c.cache {
+		if ele.Value.(*entry).Expired(now) {
+			expired = append(expired, ele)
+		}
+	}
+	for _, ele := range expired {
+		c.removeElement(ele, policy.Expired)
+	}
+	return len(expired)
+}
+
+func (c *Cache) Len() int {
+	return len(c.cache)
+}
+
+func (c *Cache) Bytes() int64 {
+	return c.nBytes
+}
+
+func (c *Cache) list(freq int) *list.List {
+	l, ok := c.freqs[freq]
+	if !ok {
+		l = list.New()
+		c.freqs[freq] = l
+	}
+	return l
+}
+
+// touch 把记录移动到频率加1的链表中
+func (c *Cache) touch(ele *list.Element) {
+	e := ele.Value.(*entry)
+	if c.unlink(ele) && c.minFreq == e.freq {
+		c.minFreq++
+	}
+	e.freq++
+	c.cache[e.Key] = c.list(e.freq).PushFront(e)
+}
+
+// unlink 把记录从所在的频率链表中移除，返回链表是否因此变为空
+func (c *Cache) unlink(ele *list.Element) bool {
+	e := ele.Value.(*entry)
+	l := c.freqs[e.freq]
+	l.Remove(ele)
+	if l.Len() > 0 {
+		return false
+	}
+	delete(c.freqs, e.freq)
+	return true
+}
+
+func (c *Cache) removeElement(ele *list.Element, reason policy.Reason) {
+	e := ele.Value.(*entry)
+	if c.unlink(ele) && c.minFreq == e.freq {
+		// 删除任意记录时，最小频率只能重新查找
+		c.minFreq = 0
+		for f := range c.freqs {
+			if c.minFreq == 0 || f < c.minFreq {
+				c.minFreq = f
+			}
+		}
+	}
+	delete(c.cache, e.Key)
+	c.nBytes -= e.Size()
+	policy.Notify(c.OnEvicted, c.OnRemoved, e.Key, e.Value, reason)
+}
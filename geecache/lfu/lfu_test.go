@@ -0,0 +1,52 @@
+package lfu
+
+import (
+	"testing"
+
+	"geektutu/geecache/policy"
+)
+
+type String string
+
+func (d String) Len() int {
+	return len(d)
+}
+
+func TestGet(t *testing.T) {
+	lfu := New(int64(0), nil)
+	lfu.Add("key1", String("1234"))
+	if v, ok := lfu.Get("key1"); !ok || string(v.(String)) != "1234" {
+		t.Fatalf("cache hit key1=1234 failed")
+	}
+	if _, ok := lfu.Get("key2"); ok {
+		t.Fatalf("cache miss key2 failed")
+	}
+	if !lfu.Remove("key1") || lfu.Len() != 0 || lfu.Bytes() != 0 {
+		t.Fatalf("remove key1 failed")
+	}
+}
+
+func TestRemoveLeastFrequent(t *testing.T) {
+	var evicted []string
+	lfu := New(int64(len("k1v1k2v2")), func(key string, _ policy.Value) {
+		evicted = append(evicted, key)
+	})
+	lfu.Add("k1", String("v1"))
+	lfu.Add("k2", String("v2"))
+	// k1被访问过，频率更高，添加k3时淘汰k2
+	lfu.Get("k1")
+	lfu.Add("k3", String("v3"))
+	if _, ok := lfu.Get("k2"); ok || lfu.Len() != 2 {
+		t.Fatalf("RemoveLeastFrequent k2 failed")
+	}
+	// k3和k1都被访问过，k3的频率低，添加k4时淘汰k3
+	lfu.Get("k1")
+	lfu.Get("k3")
+	lfu.Add("k4", String("v4"))
+	if _, ok := lfu.Get("k1"); !ok {
+		t.Fatalf("k1 should not be evicted")
+	}
+	if len(evicted) != 2 || evicted[0] != "k2" || evicted[1] != "k3" {
+		t.Fatalf("unexpected evicted keys %v", evicted)
+	}
+}
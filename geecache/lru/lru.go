@@ -1,6 +1,11 @@
 package lru
 
-import "container/list"
+import (
+	"container/list"
+	"time"
+
+	"geektutu/geecache/policy"
+)
 
 // 为什么我们要暴露lru中的相关函数呢？是为了让其他包也能利用吗？
 type Cache struct {
@@ -8,18 +13,25 @@ type Cache struct {
 	nBytes    int64
 	ll        *list.List
 	cache     map[string]*list.Element
-	OnEvicted func(key string, value Value)
+	OnEvicted func(key string, value Value)                       // 只在超出容量淘汰记录时调用
+	OnRemoved func(key string, value Value, reason policy.Reason) // 记录因为任何原因被删除时调用，reason说明原因
 }
 
 type Entry struct {
 	key   string
 	value Value
+	// 过期时间，零值表示永不过期
+	expire time.Time
 }
 
-type Value interface {
-	Len() int
+func (e *Entry) expired(now time.Time) bool {
+	return !e.expire.IsZero() && !now.Before(e.expire)
 }
 
+type Value = policy.Value
+
+var _ policy.Policy = (*Cache)(nil)
+
 func New(maxBytes int64, onEvicted func(string, Value)) *Cache {
 	return &Cache{
 		maxBytes:  maxBytes,
@@ -29,10 +41,15 @@ func New(maxBytes int64, onEvicted func(string, Value)) *Cache {
 	}
 }
 
+// Get 返回key对应的值，已经过期的记录会被删除并视为不存在
 func (c *Cache) Get(key string) (Value, bool) {
 	if ele, ok := c.cache[key]; ok {
-		c.ll.MoveToFront(ele)
 		kv := ele.Value.(*Entry)
+		if kv.expired(time.Now()) {
+			c.removeElement(ele, policy.Expired)
+			return nil, false
+		}
+		c.ll.MoveToFront(ele)
 		return kv.value, true
 	}
 	return nil, false
@@ -41,24 +58,57 @@ func (c *Cache) Get(key string) (Value, bool) {
 func (c *Cache) RemoveOldest() {
 	ele := c.ll.Back()
 	if ele != nil {
-		c.ll.Remove(ele)
-		kv := ele.Value.(*Entry)
-		delete(c.cache, kv.key)
-		c.nBytes -= int64(len(kv.key)) + int64(kv.value.Len())
-		if c.OnEvicted != nil {
-			c.OnEvicted(kv.key, kv.value)
+		c.removeElement(ele, policy.Evicted)
+	}
+}
+
+// RemoveExpired 删除所有已经过期的记录，返回删除的数量。
+// 过期的记录在Get时也会被删除，但不再被访问的记录只能依靠定期调用RemoveExpired释放内存
+func (c *Cache) RemoveExpired() int {
+	now := time.Now()
+	n := 0
+	for ele := c.ll.Back(); ele != nil; {
+		prev := ele.Prev()
+		if ele.Value.(*Entry).expired(now) {
+			c.removeElement(ele, policy.Expired)
+			n++
 		}
+		ele = prev
 	}
+	return n
+}
+
+// Remove 删除key对应的记录，返回记录是否存在
+func (c *Cache) Remove(key string) bool {
+	if ele, ok := c.cache[key]; ok {
+		c.removeElement(ele, policy.Removed)
+		return true
+	}
+	return false
+}
+
+func (c *Cache) removeElement(ele *list.Element, reason policy.Reason) {
+	c.ll.Remove(ele)
+	kv := ele.Value.(*Entry)
+	delete(c.cache, kv.key)
+	c.nBytes -= int64(len(kv.key)) + int64(kv.value.Len())
+	policy.Notify(c.OnEvicted, c.OnRemoved, kv.key, kv.value, reason)
 }
 
 func (c *Cache) Add(key string, value Value) {
+	c.AddWithExpire(key, value, time.Time{})
+}
+
+// AddWithExpire 添加一条在expire时过期的记录，expire为零值表示永不过期
+func (c *Cache) AddWithExpire(key string, value Value, expire time.Time) {
 	// if key exist, then update value, otherwise insert
-	if v, ok := c.Get(key); ok {
-		kv := c.cache[key].Value.(*Entry)
-		kv.value = value
-		c.nBytes += int64(kv.value.Len()) - int64(v.Len())
+	if ele, ok := c.cache[key]; ok {
+		c.ll.MoveToFront(ele)
+		kv := ele.Value.(*Entry)
+		c.nBytes += int64(value.Len()) - int64(kv.value.Len())
+		kv.value, kv.expire = value, expire
 	} else { // key non-exist， then先插入
-		kv := &Entry{key, value}
+		kv := &Entry{key, value, expire}
 		ele := c.ll.PushFront(kv)
 		c.cache[key] = ele
 		c.nBytes += int64(len(kv.key)) + int64(kv.value.Len())
@@ -73,3 +123,7 @@ func (c *Cache) Add(key string, value Value) {
 func (c *Cache) Len() int {
 	return c.ll.Len()
 }
+
+func (c *Cache) Bytes() int64 {
+	return c.nBytes
+}
@@ -3,6 +3,9 @@ package lru
 import (
 	"reflect"
 	"testing"
+	"time"
+
+	"geektutu/geecache/policy"
 )
 
 type String string
@@ -59,3 +62,38 @@ func TestOnEvicted(t *testing.T) {
 		t.Fatalf("Call OnEvicted failed, expect keys equals to %s", expect)
 	}
 }
+
+func TestExpire(t *testing.T) {
+	keys := make([]string, 0)
+	lru := New(int64(0), func(key string, value Value) {
+		t.Fatalf("OnEvicted should not be called for expired key %s", key)
+	})
+	lru.OnRemoved = func(key string, value Value, reason policy.Reason) {
+		if reason != policy.Expired {
+			t.Fatalf("expect reason Expired for %s, got %d", key, reason)
+		}
+		keys = append(keys, key)
+	}
+	lru.AddWithExpire("k1", String("v1"), time.Now().Add(-time.Second))
+	lru.AddWithExpire("k2", String("v2"), time.Now().Add(time.Hour))
+	lru.AddWithExpire("k3", String("v3"), time.Now().Add(-time.Second))
+	lru.Add("k4", String("v4"))
+
+	if _, ok := lru.Get("k1"); ok {
+		t.Fatalf("expired key k1 should miss")
+	}
+	if n := lru.RemoveExpired(); n != 1 || lru.Len() != 2 {
+		t.Fatalf("RemoveExpired should remove k3, got %d, len %d", n, lru.Len())
+	}
+	if !reflect.DeepEqual(keys, []string{"k1", "k3"}) {
+		t.Fatalf("expired keys should be reported, got %v", keys)
+	}
+	if _, ok := lru.Get("k2"); !ok {
+		t.Fatalf("cache hit k2 failed")
+	}
+	// 重新添加时更新过期时间
+	lru.AddWithExpire("k2", String("v2"), time.Now().Add(-time.Second))
+	if _, ok := lru.Get("k2"); ok || lru.nBytes != int64(len("k4v4")) {
+		t.Fatalf("k2 should expire after being updated, nBytes=%d", lru.nBytes)
+	}
+}
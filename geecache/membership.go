@@ -0,0 +1,162 @@
+package geecache
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// PeerSource 提供集群当前的节点列表，HTTPPool.Watch定期从中获取节点并增量更新hash环
+type PeerSource interface {
+	Peers() ([]string, error)
+}
+
+// PeerSourceFunc 用函数实现PeerSource
+type PeerSourceFunc func() ([]string, error)
+
+func (f PeerSourceFunc) Peers() ([]string, error) {
+	return f()
+}
+
+const (
+	// registryHeader 是GeeRegistry用来传递服务列表的header
+	registryHeader = "X-Geerpc-Server"
+	// defaultRegistryTimeout 是访问registry的超时时间，registry没有响应时不能让Watch一直阻塞
+	defaultRegistryTimeout = 5 * time.Second
+	// defaultWatchInterval 是Watch的interval小于等于0时使用的间隔
+	defaultWatchInterval = 10 * time.Second
+)
+
+// RegistrySource 从geerpc/registry的GeeRegistry获取节点列表。
+// 各个节点用registry.Heartbeat把自己的地址(比如http://127.0.0.1:8001)注册到registry，
+// 超时没有心跳的节点会被registry删除，也就会从hash环上移除
+type RegistrySource struct {
+	registry string
+	client   *http.Client
+}
+
+func NewRegistrySource(registryAddr string) *RegistrySource {
+	return &RegistrySource{
+		registry: registryAddr,
+		client:   &http.Client{Timeout: defaultRegistryTimeout},
+	}
+}
+
+func (s *RegistrySource) Peers() ([]string, error) {
+	resp, err := s.client.Get(s.registry)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("registry returned: %v", resp.Status)
+	}
+	var peers []string
+	for _, peer := range strings.Split(resp.Header.Get(registryHeader), ",") {
+		if peer = strings.TrimSpace(peer); peer != "" {
+			peers = append(peers, peer)
+		}
+	}
+	return peers, nil
+}
+
+// FileSource 从文件中读取节点列表，每行一个节点，忽略空行和#开头的注释
+type FileSource struct {
+	path string
+}
+
+func NewFileSource(path string) *FileSource {
+	return &FileSource{path: path}
+}
+
+func (s *FileSource) Peers() ([]string, error) {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var peers []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		peers = append(peers, line)
+	}
+	return peers, scanner.Err()
+}
+
+// AddPeers 向hash环中添加节点，已有的节点和它们的key不受影响
+func (p *HTTPPool) AddPeers(peers ...string) {
+	p.peers.add(peers...)
+}
+
+// RemovePeers 从hash环中删除节点，只有这些节点上的key会移动到其他节点
+func (p *HTTPPool) RemovePeers(peers ...string) {
+	p.peers.remove(peers...)
+}
+
+// sync 把hash环上的节点更新为peers，本节点总是保留在环上
+func (p *HTTPPool) sync(peers []string) {
+	wanted := map[string]bool{p.self: true}
+	for _, peer := range peers {
+		wanted[peer] = true
+	}
+	current := p.peers.members()
+	var added, removed []string
+	for peer := range wanted {
+		if !current[peer] {
+			added = append(added, peer)
+		}
+	}
+	for peer := range current {
+		if !wanted[peer] {
+			removed = append(removed, peer)
+		}
+	}
+	if len(added) > 0 {
+		p.Log("peers joined: %v", added)
+		p.AddPeers(added...)
+	}
+	if len(removed) > 0 {
+		p.Log("peers left: %v", removed)
+		p.RemovePeers(removed...)
+	}
+}
+
+// Watch 立即并且每隔interval从src获取一次节点列表，增量地更新hash环，返回的函数用于停止。
+// 获取失败时保留当前的节点，下一次再重试。interval小于等于0时使用defaultWatchInterval
+func (p *HTTPPool) Watch(src PeerSource, interval time.Duration) (stop func()) {
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+	update := func() {
+		peers, err := src.Peers()
+		if err != nil {
+			log.Println("[GeeCache] failed to refresh peers:", err)
+			return
+		}
+		p.sync(peers)
+	}
+	update()
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				update()
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
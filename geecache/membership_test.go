@@ -0,0 +1,144 @@
+package geecache
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"geektutu/geecache/consistenthash"
+	"geektutu/geerpc/registry"
+)
+
+func owners(p *HTTPPool) map[string]string {
+	owners := make(map[string]string)
+	for i := 0; i < 1000; i++ {
+		key := strconv.Itoa(i)
+		owners[key] = p.peers.locator.Get(key)
+	}
+	return owners
+}
+
+func TestAddRemovePeers(t *testing.T) {
+	p := NewHTTPPool("http://a")
+	p.AddPeers("http://a", "http://b", "http://c")
+	before := owners(p)
+
+	p.RemovePeers("http://b")
+	for key, owner := range owners(p) {
+		if before[key] != "http://b" && before[key] != owner {
+			t.Fatalf("key %s moved from %s to %s", key, before[key], owner)
+		}
+	}
+	if _, ok := p.peers.getters["http://b"]; ok {
+		t.Fatalf("getter of removed peer should be deleted")
+	}
+
+	before = owners(p)
+	p.AddPeers("http://d", "http://a")
+	for key, owner := range owners(p) {
+		if owner != before[key] && owner != "http://d" {
+			t.Fatalf("key %s moved from %s to %s", key, before[key], owner)
+		}
+	}
+}
+
+func TestFileSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "peers")
+	content := "# geecache peers\nhttp://a\n\n  http://b  \n"
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	peers, err := NewFileSource(path).Peers()
+	if err != nil || !reflect.DeepEqual(peers, []string{"http://a", "http://b"}) {
+		t.Fatalf("unexpected peers %v %v", peers, err)
+	}
+	if _, err := NewFileSource(path + ".missing").Peers(); err == nil {
+		t.Fatalf("expect error for missing file")
+	}
+}
+
+func TestRegistrySource(t *testing.T) {
+	server := httptest.NewServer(registry.NewGeeRegistry(time.Minute))
+	defer server.Close()
+
+	src := NewRegistrySource(server.URL)
+	if peers, err := src.Peers(); err != nil || len(peers) != 0 {
+		t.Fatalf("expect no peers, got %v %v", peers, err)
+	}
+	for _, peer := range []string{"http://b", "http://a"} {
+		req, _ := http.NewRequest("POST", server.URL, nil)
+		req.Header.Set(registryHeader, peer)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if peers, err := src.Peers(); err != nil || !reflect.DeepEqual(peers, []string{"http://a", "http://b"}) {
+		t.Fatalf("unexpected peers %v %v", peers, err)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	var mu sync.Mutex
+	peers := []string{"http://b", "http://c"}
+	src := PeerSourceFunc(func() ([]string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return peers, nil
+	})
+	p := NewHTTPPool("http://a")
+	stop := p.Watch(src, 10*time.Millisecond)
+	defer stop()
+
+	// 本节点总是在环上
+	want := map[string]bool{"http://a": true, "http://b": true, "http://c": true}
+	if got := p.peers.members(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expect %v, got %v", want, got)
+	}
+	mu.Lock()
+	peers = []string{"http://c", "http://d"}
+	mu.Unlock()
+	want = map[string]bool{"http://a": true, "http://c": true, "http://d": true}
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(5 * time.Millisecond) {
+		got := p.peers.members()
+		if reflect.DeepEqual(got, want) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expect %v, got %v", want, got)
+		}
+	}
+
+	// interval小于等于0时使用默认的间隔，而不是panic
+	NewHTTPPool("http://a").Watch(src, 0)()
+}
+
+func TestPoolLocator(t *testing.T) {
+	opt := DefaultHTTPPoolOption
+	opt.Locator = func() consistenthash.Locator { return consistenthash.NewRendezvous(nil) }
+	p := NewHTTPPool("http://a", &opt)
+	p.AddPeers("http://a", "http://b", "http://c")
+	if _, ok := p.peers.locator.(*consistenthash.Rendezvous); !ok {
+		t.Fatalf("expect rendezvous locator, got %T", p.peers.locator)
+	}
+	want := consistenthash.NewRendezvous(nil)
+	want.Add("http://a", "http://b", "http://c")
+	for i := 0; i < 100; i++ {
+		key := strconv.Itoa(i)
+		peer, ok := p.PickPeer(key)
+		if owner := want.Get(key); owner == "http://a" {
+			if ok {
+				t.Fatalf("key %s belongs to self", key)
+			}
+		} else if !ok || peer != p.peers.getters[owner] {
+			t.Fatalf("key %s should be picked from %s", key, owner)
+		}
+	}
+}
@@ -1,6 +1,10 @@
 package geecache
 
-import pb "geektutu/geecache/geecachepb"
+import (
+	"context"
+
+	pb "geektutu/geecache/geecachepb"
+)
 
 // 客户端可能首先会根据key找到存放的peer节点，然后从该peer节点中获取数据，但也有可能本地的原因，选择了其他peer节点：
 // 对于一个peer节点来说，如果该key已缓存，则直接返回，否则需要先判断该key是本地还是远程节点
@@ -19,3 +23,25 @@ type PeerPicker interface {
 type PeerGetter interface {
 	Get(*pb.Request, *pb.Response) error
 }
+
+// PeerRemover 是可以删除其他节点上缓存的PeerGetter，收到请求的节点只删除自己的缓存，不再转发
+type PeerRemover interface {
+	Remove(ctx context.Context, req *pb.RemoveRequest) error
+}
+
+// PeerSetter 是可以把值写入其他节点的PeerGetter，收到请求的节点直接写入自己的缓存
+type PeerSetter interface {
+	Set(*pb.SetRequest, *pb.SetResponse) error
+}
+
+// PeerOwner 是可以找到key所属节点的PeerPicker。与PickPeer不同，它不会用后继节点代替熔断的节点，
+// 写入和删除必须由所属节点处理，交给后继节点只会留下一份不会被访问的副本
+type PeerOwner interface {
+	// OwnerPeer 返回key所属的节点，属于本节点时ok为false，所属节点不可用时返回错误
+	OwnerPeer(key string) (peer PeerGetter, ok bool, err error)
+}
+
+// PeerLister 是可以列出其他所有节点的PeerPicker，用于广播删除
+type PeerLister interface {
+	Peers() []PeerGetter
+}
@@ -0,0 +1,181 @@
+package geecache
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"geektutu/geecache/consistenthash"
+)
+
+// breakerGetter 是带有熔断器的PeerGetter，httpGetter和rpcGetter都实现了它
+type breakerGetter interface {
+	PeerGetter
+	getBreaker() *breaker
+}
+
+// peerSet 是HTTPPool和RPCPool共用的节点集合：用Locator把key映射到节点，
+// 为每个节点保存一个getter，选择节点时跳过熔断的节点
+type peerSet struct {
+	self      string
+	locatorFn func() consistenthash.Locator
+	newGetter func(peer string) breakerGetter
+	log       func(format string, data ...interface{})
+
+	mu sync.Mutex
+	// locator记录所有的服务节点，由locatorFn创建
+	locator consistenthash.Locator
+	getters map[string]breakerGetter
+}
+
+func newPeerSet(self string, locatorFn func() consistenthash.Locator,
+	newGetter func(peer string) breakerGetter, log func(format string, data ...interface{})) *peerSet {
+	return &peerSet{self: self, locatorFn: locatorFn, newGetter: newGetter, log: log}
+}
+
+// newLocator 用fn创建Locator，fn为nil时使用consistenthash.Map
+func newLocator(fn func() consistenthash.Locator) consistenthash.Locator {
+	if fn != nil {
+		return fn()
+	}
+	return consistenthash.New(defaultReplicas, nil)
+}
+
+// closeGetter 关闭持有连接的getter，比如rpcGetter
+func closeGetter(g breakerGetter) {
+	if c, ok := g.(io.Closer); ok {
+		_ = c.Close()
+	}
+}
+
+// set 把节点设置为peers，已有节点的getter会被复用，被移除的节点的getter会被关闭
+func (s *peerSet) set(peers ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.locator = newLocator(s.locatorFn)
+	s.locator.Add(peers...)
+
+	getters := make(map[string]breakerGetter, len(peers))
+	for _, peer := range peers {
+		if _, ok := getters[peer]; ok {
+			continue
+		}
+		if g, ok := s.getters[peer]; ok {
+			getters[peer] = g
+			delete(s.getters, peer)
+		} else {
+			getters[peer] = s.newGetter(peer)
+		}
+	}
+	for _, g := range s.getters {
+		closeGetter(g)
+	}
+	s.getters = getters
+}
+
+// add 添加节点，已有的节点和它们的key不受影响
+func (s *peerSet) add(peers ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.locator == nil {
+		s.locator = newLocator(s.locatorFn)
+		s.getters = make(map[string]breakerGetter)
+	}
+	for _, peer := range peers {
+		if _, ok := s.getters[peer]; ok {
+			continue
+		}
+		s.locator.Add(peer)
+		s.getters[peer] = s.newGetter(peer)
+	}
+}
+
+// remove 删除节点，只有这些节点上的key会移动到其他节点
+func (s *peerSet) remove(peers ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.locator == nil {
+		return
+	}
+	for _, peer := range peers {
+		g, ok := s.getters[peer]
+		if !ok {
+			continue
+		}
+		s.locator.Remove(peer)
+		delete(s.getters, peer)
+		closeGetter(g)
+	}
+}
+
+// members 返回所有节点
+func (s *peerSet) members() map[string]bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	members := make(map[string]bool, len(s.getters))
+	for peer := range s.getters {
+		members[peer] = true
+	}
+	return members
+}
+
+// pick 根据key找到适当的getter，key属于自己时返回nil, false
+func (s *peerSet) pick(key string) (PeerGetter, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.locator == nil {
+		return nil, false
+	}
+	// 沿hash环依次查找，跳过熔断的节点，由下一个节点暂时代替它
+	for _, peer := range s.locator.GetN(key, len(s.getters)) {
+		if peer == s.self {
+			return nil, false
+		}
+		if getter := s.getters[peer]; getter.getBreaker().allow() {
+			s.log("Pick peer %s", peer)
+			return getter, true
+		}
+		s.log("Peer %s is unavailable, try next", peer)
+	}
+	return nil, false
+}
+
+// owner 返回key所属的节点，所属节点熔断时返回错误，而不是交给后继节点
+func (s *peerSet) owner(key string) (PeerGetter, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.locator == nil {
+		return nil, false, nil
+	}
+	peer := s.locator.Get(key)
+	if peer == "" || peer == s.self {
+		return nil, false, nil
+	}
+	getter := s.getters[peer]
+	if !getter.getBreaker().allow() {
+		return nil, false, fmt.Errorf("geecache: peer %s is unavailable", peer)
+	}
+	return getter, true, nil
+}
+
+// others 返回除自己之外的所有节点
+func (s *peerSet) others() []PeerGetter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	peers := make([]PeerGetter, 0, len(s.getters))
+	for peer, getter := range s.getters {
+		if peer != s.self {
+			peers = append(peers, getter)
+		}
+	}
+	return peers
+}
+
+// close 关闭所有节点的getter
+func (s *peerSet) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, g := range s.getters {
+		closeGetter(g)
+	}
+}
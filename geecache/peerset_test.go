@@ -0,0 +1,46 @@
+package geecache
+
+import (
+	"testing"
+
+	pb "geektutu/geecache/geecachepb"
+)
+
+// closingGetter 记录是否被关闭
+type closingGetter struct {
+	closed bool
+}
+
+func (g *closingGetter) Get(*pb.Request, *pb.Response) error { return nil }
+
+func (g *closingGetter) getBreaker() *breaker { return nil }
+
+func (g *closingGetter) Close() error {
+	g.closed = true
+	return nil
+}
+
+func TestPeerSetReuseGetters(t *testing.T) {
+	created := 0
+	s := newPeerSet("a", nil, func(string) breakerGetter {
+		created++
+		return &closingGetter{}
+	}, t.Logf)
+	s.set("a", "b", "c")
+	b, c := s.getters["b"].(*closingGetter), s.getters["c"].(*closingGetter)
+
+	s.set("a", "b", "d")
+	if created != 4 || s.getters["b"] != b {
+		t.Fatalf("getters of existing peers should be reused, created %d", created)
+	}
+	if !c.closed || b.closed {
+		t.Fatalf("only getters of removed peers should be closed")
+	}
+	if len(s.others()) != 2 {
+		t.Fatalf("expect 2 other peers, got %d", len(s.others()))
+	}
+	s.remove("b")
+	if !b.closed || s.members()["b"] {
+		t.Fatalf("removed peer should be closed and dropped")
+	}
+}
@@ -0,0 +1,65 @@
+// Package policy 定义了缓存淘汰策略的公共接口，lru、lfu、arc和twoq都实现了它
+package policy
+
+import "time"
+
+// Value 是缓存的值，Len返回它占用的字节数
+type Value interface {
+	Len() int
+}
+
+// Policy 是按字节数限制容量的缓存，超出容量时按各自的策略淘汰记录。
+// 实现不是并发安全的，由调用方加锁
+type Policy interface {
+	Add(key string, value Value)
+	// AddWithExpire 添加一条在expire时过期的记录，expire为零值表示永不过期
+	AddWithExpire(key string, value Value, expire time.Time)
+	// Get 返回key对应的值，已经过期的记录会被删除并视为不存在
+	Get(key string) (Value, bool)
+	Remove(key string) bool
+	// RemoveExpired 删除所有已经过期的记录，返回删除的数量
+	RemoveExpired() int
+	Len() int
+	// Bytes 返回当前缓存的记录占用的字节数，包括key
+	Bytes() int64
+}
+
+// Reason 说明记录被删除的原因
+type Reason int
+
+const (
+	// Evicted 超出容量被淘汰
+	Evicted Reason = iota
+	// Expired 已经过期，在Get或者RemoveExpired时删除
+	Expired
+	// Removed 被Remove删除
+	Removed
+)
+
+// Notify 在记录被删除时调用各个策略的回调：onEvicted只在超出容量被淘汰时调用，
+// onRemoved收到所有的删除以及原因，两者都可以为nil
+func Notify(onEvicted func(string, Value), onRemoved func(string, Value, Reason), key string, value Value, reason Reason) {
+	if reason == Evicted && onEvicted != nil {
+		onEvicted(key, value)
+	}
+	if onRemoved != nil {
+		onRemoved(key, value, reason)
+	}
+}
+
+// Entry 是各个策略中保存的一条记录
+type Entry struct {
+	Key    string
+	Value  Value
+	Expire time.Time
+}
+
+// Size 返回记录占用的字节数
+func (e *Entry) Size() int64 {
+	return int64(len(e.Key)) + int64(e.Value.Len())
+}
+
+// Expired 判断记录在now时是否已经过期
+func (e *Entry) Expired(now time.Time) bool {
+	return !e.Expire.IsZero() && !now.Before(e.Expire)
+}
@@ -0,0 +1,162 @@
+package policy_test
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+	"time"
+
+	"geektutu/geecache/arc"
+	"geektutu/geecache/lfu"
+	"geektutu/geecache/lru"
+	"geektutu/geecache/policy"
+	"geektutu/geecache/twoq"
+)
+
+type String string
+
+func (d String) Len() int {
+	return len(d)
+}
+
+var policies = []struct {
+	name string
+	new  func(maxBytes int64) policy.Policy
+}{
+	{"LRU", func(n int64) policy.Policy { return lru.New(n, nil) }},
+	{"LFU", func(n int64) policy.Policy { return lfu.New(n, nil) }},
+	{"ARC", func(n int64) policy.Policy { return arc.New(n, nil) }},
+	{"2Q", func(n int64) policy.Policy { return twoq.New(n, nil) }},
+}
+
+const (
+	numKeys = 10000
+	// 每条记录为"k0000v0000"的形式，都是10个字节
+	entrySize = 10
+)
+
+// zipfKeys 生成服从Zipf分布的访问序列，少数key占了大部分的访问
+func zipfKeys(n int, s float64) []string {
+	r := rand.New(rand.NewSource(1))
+	z := rand.NewZipf(r, s, 1, numKeys-1)
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = "k" + strconv.Itoa(int(z.Uint64()) + 10000)[1:]
+	}
+	return keys
+}
+
+// hitRatio 模拟未命中时加载并添加的使用方式，返回命中率
+func hitRatio(p policy.Policy, keys []string) float64 {
+	hits := 0
+	for _, k := range keys {
+		if _, ok := p.Get(k); ok {
+			hits++
+			continue
+		}
+		p.Add(k, String("v"+k[1:]))
+	}
+	return float64(hits) / float64(len(keys))
+}
+
+func TestZipfHitRatio(t *testing.T) {
+	keys := zipfKeys(100000, 1.1)
+	for _, capacity := range []int64{100, 500, 2000} {
+		ratios := make(map[string]float64)
+		for _, p := range policies {
+			c := p.new(capacity * entrySize)
+			ratios[p.name] = hitRatio(c, keys)
+			if c.Bytes() > capacity*entrySize {
+				t.Fatalf("%s exceeds capacity: %d > %d", p.name, c.Bytes(), capacity*entrySize)
+			}
+			t.Logf("capacity=%d %s hit ratio %.4f", capacity, p.name, ratios[p.name])
+		}
+		// 访问分布稳定且倾斜时，考虑访问频率的策略应当优于LRU
+		for _, name := range []string{"LFU", "ARC", "2Q"} {
+			if ratios[name] <= ratios["LRU"] {
+				t.Errorf("capacity=%d %s hit ratio %.4f should beat LRU %.4f", capacity, name, ratios[name], ratios["LRU"])
+			}
+		}
+	}
+}
+
+func BenchmarkZipf(b *testing.B) {
+	keys := zipfKeys(1<<16, 1.1)
+	for _, p := range policies {
+		b.Run(p.name, func(b *testing.B) {
+			c := p.new(500 * entrySize)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				k := keys[i&(len(keys)-1)]
+				if _, ok := c.Get(k); !ok {
+					c.Add(k, String("v"+k[1:]))
+				}
+			}
+		})
+	}
+}
+
+func TestRemoveReasons(t *testing.T) {
+	type callbacks struct {
+		evicted func(string, policy.Value)
+		removed func(string, policy.Value, policy.Reason)
+	}
+	newPolicies := []struct {
+		name string
+		new  func(maxBytes int64, cb callbacks) policy.Policy
+	}{
+		{"LRU", func(n int64, cb callbacks) policy.Policy {
+			c := lru.New(n, cb.evicted)
+			c.OnRemoved = cb.removed
+			return c
+		}},
+		{"LFU", func(n int64, cb callbacks) policy.Policy {
+			c := lfu.New(n, cb.evicted)
+			c.OnRemoved = cb.removed
+			return c
+		}},
+		{"ARC", func(n int64, cb callbacks) policy.Policy {
+			c := arc.New(n, cb.evicted)
+			c.OnRemoved = cb.removed
+			return c
+		}},
+		{"2Q", func(n int64, cb callbacks) policy.Policy {
+			c := twoq.New(n, cb.evicted)
+			c.OnRemoved = cb.removed
+			return c
+		}},
+	}
+	for _, p := range newPolicies {
+		var evicted []string
+		reasons := make(map[policy.Reason][]string)
+		c := p.new(2*entrySize, callbacks{
+			evicted: func(key string, _ policy.Value) { evicted = append(evicted, key) },
+			removed: func(key string, _ policy.Value, reason policy.Reason) { reasons[reason] = append(reasons[reason], key) },
+		})
+		c.AddWithExpire("k0001", String("v0001"), time.Now().Add(-time.Second))
+		c.Add("k0002", String("v0002"))
+		if _, ok := c.Get("k0001"); ok {
+			t.Fatalf("%s: expired key should miss", p.name)
+		}
+		c.Remove("k0002")
+		for _, k := range []string{"k0003", "k0004", "k0005"} {
+			c.Add(k, String("v"+k[1:]))
+		}
+		if len(reasons[policy.Expired]) != 1 || reasons[policy.Expired][0] != "k0001" {
+			t.Errorf("%s: expect k0001 expired, got %v", p.name, reasons[policy.Expired])
+		}
+		if len(reasons[policy.Removed]) != 1 || reasons[policy.Removed][0] != "k0002" {
+			t.Errorf("%s: expect k0002 removed, got %v", p.name, reasons[policy.Removed])
+		}
+		// OnEvicted只报告超出容量的淘汰
+		if len(evicted) == 0 || len(evicted) != len(reasons[policy.Evicted]) {
+			t.Errorf("%s: OnEvicted got %v, OnRemoved got evictions %v", p.name, evicted, reasons[policy.Evicted])
+		}
+		for _, k := range evicted {
+			if k == "k0001" || k == "k0002" {
+				t.Errorf("%s: %s should not be reported as evicted", p.name, k)
+			}
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package geecache
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"geektutu/geecache/consistenthash"
+	pb "geektutu/geecache/geecachepb"
+	"geektutu/geerpc"
+)
+
+// GeeCache 是通过geerpc提供缓存的服务，与HTTPPool.ServeHTTP相对应。
+// 在geerpc.Server上注册后，RPCPool就可以通过GeeCache.Get等方法访问本节点：
+//
+//	server := geerpc.NewServer()
+//	server.Register(&geecache.GeeCache{})
+//
+// gob无法编码没有导出字段的类型，因此Remove和Set的返回值用*bool代替pb中的空消息
+type GeeCache struct{}
+
+func (s *GeeCache) Get(req *pb.Request, resp *pb.Response) error {
+	group, err := serverGroup(req.Group)
+	if err != nil {
+		return err
+	}
+	bv, err := group.serve(req.Key)
+	if err != nil {
+		return err
+	}
+	resp.Value, resp.Expire = bv.ByteSlice(), bv.expireNano()
+	return nil
+}
+
+// Remove 只删除本地的缓存，不再转发
+func (s *GeeCache) Remove(req *pb.RemoveRequest, ok *bool) error {
+	group, err := serverGroup(req.Group)
+	if err != nil {
+		return err
+	}
+	group.removeLocally(req.Key)
+	*ok = true
+	return nil
+}
+
+// Set 直接写入本地的mainCache，不再转发
+func (s *GeeCache) Set(req *pb.SetRequest, ok *bool) error {
+	group, err := serverGroup(req.Group)
+	if err != nil {
+		return err
+	}
+	group.mainCache.add(req.Key, newByteView(req.Value, req.Expire))
+	*ok = true
+	return nil
+}
+
+func serverGroup(name string) (*Group, error) {
+	group := GetGroup(name)
+	if group == nil {
+		return nil, fmt.Errorf("geecache: invalid group %s", name)
+	}
+	group.stats.ServerRequests.Add(1)
+	return group, nil
+}
+
+// RPCPool 是通过geerpc访问其他节点的PeerPicker，与HTTPPool相对应。
+// 节点使用geerpc.XDial的地址格式表示，比如tcp@127.0.0.1:9999，
+// 每个节点复用一个geerpc.Client，连接断开后在下一次访问时重新连接
+type RPCPool struct {
+	self  string
+	opt   RPCPoolOption
+	peers *peerSet
+}
+
+// RPCPoolOption 是RPCPool访问其他节点时的可选配置，超时和熔断的含义与HTTPPoolOption相同
+type RPCPoolOption struct {
+	// Option 用于连接其他节点，为nil时使用geerpc.DefaultOption
+	Option *geerpc.Option
+	// Timeout 每次调用其他节点的超时时间，为0表示不超时
+	Timeout time.Duration
+	// FailureThreshold 节点连续失败多少次后熔断，小于等于0表示不熔断
+	FailureThreshold int
+	// Cooldown 节点熔断的时间，之后重新尝试访问
+	Cooldown time.Duration
+	// Locator 创建把key映射到节点的Locator，为nil时使用defaultReplicas倍虚拟节点的consistenthash.Map
+	Locator func() consistenthash.Locator
+}
+
+var DefaultRPCPoolOption = RPCPoolOption{
+	Timeout:          3 * time.Second,
+	FailureThreshold: 5,
+	Cooldown:         10 * time.Second,
+}
+
+// NewRPCPool 创建一个RPCPool，self是本节点的地址，opts最多只能有一个，为空时使用DefaultRPCPoolOption
+func NewRPCPool(self string, opts ...*RPCPoolOption) *RPCPool {
+	if len(opts) > 1 {
+		panic("should specify at most 1 rpc pool option")
+	}
+	opt := DefaultRPCPoolOption
+	if len(opts) == 1 && opts[0] != nil {
+		opt = *opts[0]
+	}
+	p := &RPCPool{self: self, opt: opt}
+	p.peers = newPeerSet(self, opt.Locator, func(peer string) breakerGetter { return p.newGetter(peer) }, p.Log)
+	return p
+}
+
+func (p *RPCPool) Log(format string, data ...interface{}) {
+	log.Printf("[RPC Server %s] %s", p.self, fmt.Sprintf(format, data...))
+}
+
+// Set 设置所有节点，已有节点的连接会被复用
+// Set 设置所有节点，已有节点的连接会被复用，被移除的节点的连接会被关闭
+func (p *RPCPool) Set(peers ...string) {
+	p.peers.set(peers...)
+}
+
+// PickPeer 与HTTPPool相同，跳过熔断的节点，由hash环上的下一个节点暂时代替它
+func (p *RPCPool) PickPeer(key string) (PeerGetter, bool) {
+	return p.peers.pick(key)
+}
+
+// OwnerPeer 返回key所属的节点，所属节点熔断时返回错误，而不是交给后继节点
+func (p *RPCPool) OwnerPeer(key string) (PeerGetter, bool, error) {
+	return p.peers.owner(key)
+}
+
+func (p *RPCPool) newGetter(peer string) *rpcGetter {
+	return &rpcGetter{
+		addr:    peer,
+		opt:     p.opt.Option,
+		timeout: p.opt.Timeout,
+		breaker: newBreaker(p.opt.FailureThreshold, p.opt.Cooldown),
+	}
+}
+
+// Peers 返回除自己之外的所有节点
+func (p *RPCPool) Peers() []PeerGetter {
+	return p.peers.others()
+}
+
+// Close 关闭到所有节点的连接
+func (p *RPCPool) Close() error {
+	p.peers.close()
+	return nil
+}
+
+var _ PeerPicker = (*RPCPool)(nil)
+var _ PeerOwner = (*RPCPool)(nil)
+var _ PeerLister = (*RPCPool)(nil)
+
+// rpcGetter 通过一个复用的geerpc.Client访问一个节点
+type rpcGetter struct {
+	addr string
+	opt  *geerpc.Option
+	// timeout为0表示不超时
+	timeout time.Duration
+	// breaker为nil表示不熔断
+	breaker *breaker
+
+	mu     sync.Mutex
+	client *geerpc.Client
+}
+
+// getClient 返回可用的client，没有连接或者连接已经断开时重新连接
+func (g *rpcGetter) getClient() (*geerpc.Client, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.client != nil && g.client.IsAlive() {
+		return g.client, nil
+	}
+	if g.client != nil {
+		_ = g.client.Close()
+		g.client = nil
+	}
+	var opts []*geerpc.Option
+	if g.opt != nil {
+		opts = append(opts, g.opt)
+	}
+	client, err := geerpc.XDial(g.addr, opts...)
+	if err != nil {
+		return nil, err
+	}
+	g.client = client
+	return client, nil
+}
+
+// call 调用节点的方法，并根据结果更新熔断器：连接失败、超时和连接断开视为节点故障，
+// 节点返回的错误说明节点是正常的
+func (g *rpcGetter) call(ctx context.Context, serviceMethod string, args, reply interface{}) error {
+	if g.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.timeout)
+		defer cancel()
+	}
+	client, err := g.getClient()
+	if err != nil {
+		g.breaker.failure()
+		return err
+	}
+	err = client.Call(ctx, serviceMethod, args, reply)
+	if err != nil && (ctx.Err() != nil || !client.IsAlive()) {
+		g.breaker.failure()
+	} else {
+		g.breaker.success()
+	}
+	return err
+}
+
+func (g *rpcGetter) Get(req *pb.Request, resp *pb.Response) error {
+	return g.call(context.Background(), "GeeCache.Get", req, resp)
+}
+
+func (g *rpcGetter) Remove(ctx context.Context, req *pb.RemoveRequest) error {
+	var ok bool
+	return g.call(ctx, "GeeCache.Remove", req, &ok)
+}
+
+func (g *rpcGetter) Set(req *pb.SetRequest, resp *pb.SetResponse) error {
+	var ok bool
+	return g.call(context.Background(), "GeeCache.Set", req, &ok)
+}
+
+func (g *rpcGetter) getBreaker() *breaker {
+	return g.breaker
+}
+
+func (g *rpcGetter) Close() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.client == nil {
+		return nil
+	}
+	err := g.client.Close()
+	g.client = nil
+	return err
+}
+
+var _ PeerGetter = (*rpcGetter)(nil)
+var _ PeerRemover = (*rpcGetter)(nil)
+var _ PeerSetter = (*rpcGetter)(nil)
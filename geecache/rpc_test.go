@@ -0,0 +1,108 @@
+package geecache
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	pb "geektutu/geecache/geecachepb"
+	"geektutu/geerpc"
+)
+
+func startRPCServer(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := geerpc.NewServer()
+	if err := server.Register(&GeeCache{}); err != nil {
+		t.Fatal(err)
+	}
+	go server.Accept(l)
+	t.Cleanup(func() { l.Close() })
+	return "tcp@" + l.Addr().String()
+}
+
+func TestRPCPool(t *testing.T) {
+	gee := NewGroup("rpc", 2<<10, GetterFunc(anonymousFunc))
+	addr := startRPCServer(t)
+	pool := NewRPCPool("tcp@self")
+	pool.Set(addr)
+	defer pool.Close()
+
+	peer, ok := pool.PickPeer("Tom")
+	if !ok {
+		t.Fatalf("expect to pick remote peer %s", addr)
+	}
+	resp := &pb.Response{}
+	if err := peer.Get(&pb.Request{Group: "rpc", Key: "Tom"}, resp); err != nil || string(resp.Value) != "Tom" {
+		t.Fatalf("failed to get Tom through rpc: %v %s", err, resp.Value)
+	}
+	client := peer.(*rpcGetter).client
+	if err := peer.(PeerSetter).Set(&pb.SetRequest{Group: "rpc", Key: "Jack", Value: []byte("589")}, &pb.SetResponse{}); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := gee.mainCache.get("Jack"); !ok || v.String() != "589" {
+		t.Fatalf("failed to set Jack through rpc")
+	}
+	if err := peer.(PeerRemover).Remove(context.Background(), &pb.RemoveRequest{Group: "rpc", Key: "Jack"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := gee.mainCache.get("Jack"); ok {
+		t.Fatalf("failed to remove Jack through rpc")
+	}
+	// 同一个节点复用同一个连接
+	if peer.(*rpcGetter).client != client {
+		t.Fatalf("client should be reused")
+	}
+	if err := peer.Get(&pb.Request{Group: "unknown", Key: "Tom"}, resp); err == nil {
+		t.Fatalf("expect error for unknown group")
+	}
+	if n := gee.Stats().ServerRequests; n != 3 {
+		t.Fatalf("expect 3 server requests, got %d", n)
+	}
+
+	// 只有自己时不从其他节点获取
+	pool.Set("tcp@self")
+	if _, ok := pool.PickPeer("Tom"); ok {
+		t.Fatalf("should not pick self")
+	}
+}
+
+func TestRPCPoolBreaker(t *testing.T) {
+	NewGroup("rpcslow", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		time.Sleep(200 * time.Millisecond)
+		return []byte(key), nil
+	}))
+	addr := startRPCServer(t)
+	pool := NewRPCPool("tcp@self", &RPCPoolOption{Timeout: 20 * time.Millisecond, FailureThreshold: 1, Cooldown: time.Minute})
+	pool.Set("tcp@self", addr)
+	defer pool.Close()
+
+	var key string
+	for i := 0; key == ""; i++ {
+		if k := "key" + strconv.Itoa(i); pool.peers.locator.Get(k) == addr {
+			key = k
+		}
+	}
+	peer, ok := pool.PickPeer(key)
+	if !ok {
+		t.Fatalf("expect to pick remote peer %s", addr)
+	}
+	start := time.Now()
+	if err := peer.Get(&pb.Request{Group: "rpcslow", Key: key}, &pb.Response{}); err == nil {
+		t.Fatalf("expect timeout error")
+	}
+	if d := time.Since(start); d > 150*time.Millisecond {
+		t.Fatalf("call should time out quickly, took %v", d)
+	}
+	// 节点熔断后由本节点加载，写入和删除则返回错误
+	if _, ok := pool.PickPeer(key); ok {
+		t.Fatalf("expect local load when the peer is open")
+	}
+	if _, ok, err := pool.OwnerPeer(key); ok || err == nil {
+		t.Fatalf("expect error when the owner is open")
+	}
+}
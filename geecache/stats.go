@@ -0,0 +1,168 @@
+package geecache
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// statsPath 是HTTPPool提供统计数据的路径，即/_geecache/_stats
+const statsPath = "_stats"
+
+// AtomicInt 是并发安全的计数器
+type AtomicInt int64
+
+func (i *AtomicInt) Add(n int64) {
+	atomic.AddInt64((*int64)(i), n)
+}
+
+func (i *AtomicInt) Get() int64 {
+	return atomic.LoadInt64((*int64)(i))
+}
+
+func (i *AtomicInt) String() string {
+	return strconv.FormatInt(i.Get(), 10)
+}
+
+// groupStats 是Group的计数器
+type groupStats struct {
+	Gets           AtomicInt
+	CacheHits      AtomicInt
+	PeerLoads      AtomicInt
+	PeerErrors     AtomicInt
+	LocalLoads     AtomicInt
+	LocalLoadErrs  AtomicInt
+	LoadsDeduped   AtomicInt
+	ServerRequests AtomicInt
+}
+
+// Stats 是Group统计数据的快照
+type Stats struct {
+	// Gets Get的调用次数，包括来自其他节点的请求
+	Gets int64 `json:"gets"`
+	// CacheHits mainCache或hotCache命中的次数
+	CacheHits int64 `json:"cache_hits"`
+	// PeerLoads 从其他节点成功获取的次数
+	PeerLoads  int64 `json:"peer_loads"`
+	PeerErrors int64 `json:"peer_errors"`
+	// LocalLoads 从本地的Getter成功加载的次数
+	LocalLoads    int64 `json:"local_loads"`
+	LocalLoadErrs int64 `json:"local_load_errs"`
+	// LoadsDeduped 与并发的相同请求合并，没有重复加载的次数
+	LoadsDeduped int64 `json:"loads_deduped"`
+	// ServerRequests 通过HTTP收到的其他节点的请求数
+	ServerRequests int64 `json:"server_requests"`
+
+	MainCache CacheStats `json:"main_cache"`
+	HotCache  CacheStats `json:"hot_cache"`
+}
+
+// CacheStats 是mainCache或hotCache的统计数据
+type CacheStats struct {
+	Bytes int64 `json:"bytes"`
+	Items int64 `json:"items"`
+	Gets  int64 `json:"gets"`
+	Hits  int64 `json:"hits"`
+	// Evictions 因容量不足被淘汰的记录数
+	Evictions int64 `json:"evictions"`
+	// Expirations 过期被删除的记录数
+	Expirations int64 `json:"expirations"`
+	// Removals 调用Remove删除的记录数
+	Removals int64 `json:"removals"`
+}
+
+// Stats 返回Group当前的统计数据
+func (g *Group) Stats() Stats {
+	return Stats{
+		Gets:           g.stats.Gets.Get(),
+		CacheHits:      g.stats.CacheHits.Get(),
+		PeerLoads:      g.stats.PeerLoads.Get(),
+		PeerErrors:     g.stats.PeerErrors.Get(),
+		LocalLoads:     g.stats.LocalLoads.Get(),
+		LocalLoadErrs:  g.stats.LocalLoadErrs.Get(),
+		LoadsDeduped:   g.stats.LoadsDeduped.Get(),
+		ServerRequests: g.stats.ServerRequests.Get(),
+		MainCache:      g.mainCache.stats(),
+		HotCache:       g.hotCache.stats(),
+	}
+}
+
+// allStats 返回所有Group的统计数据
+func allStats() map[string]Stats {
+	mu.RLock()
+	defer mu.RUnlock()
+	stats := make(map[string]Stats, len(groups))
+	for name, g := range groups {
+		stats[name] = g.Stats()
+	}
+	return stats
+}
+
+// serveStats 返回所有Group的统计数据。
+// 默认返回JSON，请求带有format=prometheus参数或者Accept为text/plain时，返回Prometheus的文本格式
+func (p *HTTPPool) serveStats(w http.ResponseWriter, r *http.Request) {
+	stats := allStats()
+	if r.URL.Query().Get("format") == "prometheus" || strings.Contains(r.Header.Get("Accept"), "text/plain") {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		writePrometheus(w, stats)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(stats); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+var groupMetrics = []struct {
+	name, typ, help string
+	value           func(s Stats) int64
+}{
+	{"geecache_gets_total", "counter", "Number of Get calls.", func(s Stats) int64 { return s.Gets }},
+	{"geecache_hits_total", "counter", "Number of gets served from main or hot cache.", func(s Stats) int64 { return s.CacheHits }},
+	{"geecache_peer_loads_total", "counter", "Number of values loaded from peers.", func(s Stats) int64 { return s.PeerLoads }},
+	{"geecache_peer_errors_total", "counter", "Number of failed peer loads.", func(s Stats) int64 { return s.PeerErrors }},
+	{"geecache_local_loads_total", "counter", "Number of values loaded by the local getter.", func(s Stats) int64 { return s.LocalLoads }},
+	{"geecache_local_load_errors_total", "counter", "Number of failed local loads.", func(s Stats) int64 { return s.LocalLoadErrs }},
+	{"geecache_loads_deduped_total", "counter", "Number of loads merged into a concurrent identical load.", func(s Stats) int64 { return s.LoadsDeduped }},
+	{"geecache_server_requests_total", "counter", "Number of requests received from peers.", func(s Stats) int64 { return s.ServerRequests }},
+}
+
+var cacheMetrics = []struct {
+	name, typ, help string
+	value           func(s CacheStats) int64
+}{
+	{"geecache_cache_bytes", "gauge", "Bytes held by the cache.", func(s CacheStats) int64 { return s.Bytes }},
+	{"geecache_cache_items", "gauge", "Items held by the cache.", func(s CacheStats) int64 { return s.Items }},
+	{"geecache_cache_gets_total", "counter", "Number of lookups in the cache.", func(s CacheStats) int64 { return s.Gets }},
+	{"geecache_cache_hits_total", "counter", "Number of lookups hit in the cache.", func(s CacheStats) int64 { return s.Hits }},
+	{"geecache_cache_evictions_total", "counter", "Number of items evicted because the cache was full.", func(s CacheStats) int64 { return s.Evictions }},
+	{"geecache_cache_expirations_total", "counter", "Number of expired items removed.", func(s CacheStats) int64 { return s.Expirations }},
+	{"geecache_cache_removals_total", "counter", "Number of items removed by Remove.", func(s CacheStats) int64 { return s.Removals }},
+}
+
+// writePrometheus 按Prometheus的文本格式输出统计数据，group按名字排序
+func writePrometheus(w io.Writer, stats map[string]Stats) {
+	names := make([]string, 0, len(stats))
+	for name := range stats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, m := range groupMetrics {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.typ)
+		for _, name := range names {
+			fmt.Fprintf(w, "%s{group=%q} %d\n", m.name, name, m.value(stats[name]))
+		}
+	}
+	for _, m := range cacheMetrics {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.typ)
+		for _, name := range names {
+			fmt.Fprintf(w, "%s{group=%q,cache=\"main\"} %d\n", m.name, name, m.value(stats[name].MainCache))
+			fmt.Fprintf(w, "%s{group=%q,cache=\"hot\"} %d\n", m.name, name, m.value(stats[name].HotCache))
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package geecache
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	gee := NewGroup("stats", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		if key == "unknown" {
+			return nil, fmt.Errorf("%s not exist", key)
+		}
+		time.Sleep(50 * time.Millisecond)
+		return []byte(key), nil
+	}))
+	// 并发获取同一个key，只加载一次
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			gee.Get("Tom")
+		}()
+	}
+	wg.Wait()
+	gee.Get("Tom")
+	gee.Get("unknown")
+
+	s := gee.Stats()
+	if s.Gets != 7 || s.CacheHits != 1 || s.LocalLoads != 1 || s.LocalLoadErrs != 1 {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+	if s.LoadsDeduped != 4 {
+		t.Fatalf("expect 4 deduped loads, got %d", s.LoadsDeduped)
+	}
+	if s.MainCache.Items != 1 || s.MainCache.Bytes != int64(len("TomTom")) {
+		t.Fatalf("unexpected main cache stats %+v", s.MainCache)
+	}
+}
+
+func TestCacheStatsRemovals(t *testing.T) {
+	c := &cache{cacheBytes: int64(len("k1v1")) * 2}
+	c.add("k1", ByteView{b: []byte("v1")})
+	c.add("k2", ByteView{b: []byte("v2"), e: time.Now().Add(-time.Second)})
+	c.get("k2")
+	c.remove("k1")
+	for _, k := range []string{"k3", "k4", "k5"} {
+		c.add(k, ByteView{b: []byte("v")})
+	}
+	if s := c.stats(); s.Evictions != 1 || s.Expirations != 1 || s.Removals != 1 {
+		t.Fatalf("evictions, expirations and removals should be counted separately, got %+v", s)
+	}
+}
+
+func TestServeStats(t *testing.T) {
+	gee := NewGroup("served", 2<<10, GetterFunc(anonymousFunc))
+	pool := NewHTTPPool("http://localhost:9999")
+
+	r := httptest.NewRequest("GET", "/_geecache/served/Tom", nil)
+	pool.ServeHTTP(httptest.NewRecorder(), r)
+	if n := gee.Stats().ServerRequests; n != 1 {
+		t.Fatalf("expect 1 server request, got %d", n)
+	}
+
+	w := httptest.NewRecorder()
+	pool.ServeHTTP(w, httptest.NewRequest("GET", "/_geecache/_stats", nil))
+	var stats map[string]Stats
+	if err := json.Unmarshal(w.Body.Bytes(), &stats); err != nil {
+		t.Fatal(err)
+	}
+	if s := stats["served"]; s.Gets != 1 || s.LocalLoads != 1 || s.ServerRequests != 1 {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+
+	w = httptest.NewRecorder()
+	pool.ServeHTTP(w, httptest.NewRequest("GET", "/_geecache/_stats?format=prometheus", nil))
+	body := w.Body.String()
+	for _, line := range []string{
+		"# TYPE geecache_gets_total counter",
+		`geecache_server_requests_total{group="served"} 1`,
+		`geecache_cache_items{group="served",cache="main"} 1`,
+	} {
+		if !strings.Contains(body, line) {
+			t.Fatalf("expect %q in\n%s", line, body)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+// Package twoq 实现了2Q缓存淘汰算法。
+// 新记录先进入先进先出的a1in，从a1in淘汰的记录只保留key进入a1out；
+// 在a1out中的记录再次被添加时，说明它不只是偶尔访问一次，进入按LRU淘汰的am。
+// 只访问一次的记录不会挤掉am中的热点记录，因此能抵抗扫描类的访问
+package twoq
+
+import (
+	"container/list"
+	"time"
+
+	"geektutu/geecache/policy"
+)
+
+const (
+	// a1in占总容量的比例
+	defaultInRatio = 0.25
+	// a1out幽灵记录占总容量的比例
+	defaultOutRatio = 0.5
+)
+
+type Cache struct {
+	maxBytes int64
+	// a1in和a1out的字节数上限
+	kin, kout int64
+	a1in      *fifo
+	a1out     *fifo
+	am        *fifo
+	cache     map[string]*list.Element
+	OnEvicted func(key string, value policy.Value)                       // 只在超出容量淘汰记录时调用
+	OnRemoved func(key string, value policy.Value, reason policy.Reason) // 记录因为任何原因被删除时调用，reason说明原因
+}
+
+var _ policy.Policy = (*Cache)(nil)
+
+// fifo 是带字节数统计的链表，新记录插入头部，从尾部淘汰
+type fifo struct {
+	*list.List
+	nBytes int64
+}
+
+type entry struct {
+	policy.Entry
+	where *fifo
+	// a1out中的记录Value为nil，size保存被淘汰时的大小
+	size int64
+}
+
+// New 创建一个Cache，maxBytes为0表示不限制大小
+func New(maxBytes int64, onEvicted func(string, policy.Value)) *Cache {
+	return &Cache{
+		maxBytes:  maxBytes,
+		kin:       int64(float64(maxBytes) * defaultInRatio),
+		kout:      int64(float64(maxBytes) * defaultOutRatio),
+		a1in:      &fifo{List: list.New()},
+		a1out:     &fifo{List: list.New()},
+		am:        &fifo{List: list.New()},
+		cache:     make(map[string]*list.Element),
+		OnEvicted: onEvicted,
+	}
+}
+
+func (c *Cache) Get(key string) (policy.Value, bool) {
+	ele, ok := c.cache[key]
+	if !ok {
+		return nil, false
+	}
+	e := ele.Value.(*entry)
+	if e.where == c.a1out {
+		return nil, false
+	}
+	if e.Expired(time.Now()) {
+		c.removeElement(ele, policy.Expired)
+		return nil, false
+	}
+	// a1in是先进先出的，命中时不调整位置
+	if e.where == c.am {
+		c.am.MoveToFront(ele)
+	}
+	return e.Value, true
+}
+
+func (c *Cache) Add(key string, value policy.Value) {
+	c.AddWithExpire(key, value, time.Time{})
+}
+
+func (c *Cache) AddWithExpire(key string, value policy.Value, expire time.Time) {
+	size := int64(len(key)) + int64(value.Len())
+	if ele, ok := c.cache[key]; ok {
+		e := ele.Value.(*entry)
+		e.Value, e.Expire = value, expire
+		e.where.nBytes += size - e.size
+		e.size = size
+		switch e.where {
+		case c.am:
+			c.am.MoveToFront(ele)
+		case c.a1out:
+			c.move(ele, c.am)
+		}
+	} else {
+		e := &entry{Entry: policy.Entry{Key: key, Value: value, Expire: expire}, where: c.a1in, size: size}
+		c.a1in.nBytes += size
+		c.cache[key] = c.a1in.PushFront(e)
+	}
+	c.evict()
+}
+
+func (c *Cache) Remove(key string) bool {
+	ele, ok := c.cache[key]
+	if !ok || ele.Value.(*entry).where == c.a1out {
+		return false
+	}
+	c.removeElement(ele, policy.Removed)
+	return true
+}
+
+func (c *Cache) RemoveExpired() int {
+	now := time.Now()
+	n := 0
+	for _, l := range []*fifo{c.a1in, c.am} {
+		for ele := l.Back(); ele != nil; {
+			prev := ele.Prev()
+			if ele.Value.(*entry).Expired(now) {
+				c.removeElement(ele, policy.Expired)
+				n++
+			}
+			ele = prev
+		}
+	}
+	return n
+}
+
+func (c *Cache) Len() int {
+	return c.a1in.Len() + c.am.Len()
+}
+
+func (c *Cache) Bytes() int64 {
+	return c.a1in.nBytes + c.am.nBytes
+}
+
+func (c *Cache) move(ele *list.Element, to *fifo) {
+	e := ele.Value.(*entry)
+	e.where.Remove(ele)
+	e.where.nBytes -= e.size
+	e.where = to
+	to.nBytes += e.size
+	c.cache[e.Key] = to.PushFront(e)
+}
+
+// evict 淘汰记录直到不超过容量：a1in超过kin时把a1in最早的记录移入a1out，否则淘汰am中最久未访问的记录
+func (c *Cache) evict() {
+	if c.maxBytes == 0 {
+		return
+	}
+	for c.Bytes() > c.maxBytes {
+		if c.a1in.Len() > 0 && (c.a1in.nBytes > c.kin || c.am.Len() == 0) {
+			ele := c.a1in.Back()
+			e := ele.Value.(*entry)
+			value := e.Value
+			c.move(ele, c.a1out)
+			e.Value = nil
+			policy.Notify(c.OnEvicted, c.OnRemoved, e.Key, value, policy.Evicted)
+		} else {
+			c.removeElement(c.am.Back(), policy.Evicted)
+		}
+	}
+	for c.a1out.Len() > 0 && c.a1out.nBytes > c.kout {
+		c.drop(c.a1out.Back())
+	}
+}
+
+func (c *Cache) drop(ele *list.Element) {
+	e := ele.Value.(*entry)
+	e.where.Remove(ele)
+	e.where.nBytes -= e.size
+	delete(c.cache, e.Key)
+}
+
+func (c *Cache) removeElement(ele *list.Element, reason policy.Reason) {
+	e := ele.Value.(*entry)
+	c.drop(ele)
+	policy.Notify(c.OnEvicted, c.OnRemoved, e.Key, e.Value, reason)
+}
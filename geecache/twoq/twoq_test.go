@@ -0,0 +1,49 @@
+package twoq
+
+import (
+	"fmt"
+	"testing"
+)
+
+type String string
+
+func (d String) Len() int {
+	return len(d)
+}
+
+func TestGet(t *testing.T) {
+	q := New(int64(0), nil)
+	q.Add("key1", String("1234"))
+	if v, ok := q.Get("key1"); !ok || string(v.(String)) != "1234" {
+		t.Fatalf("cache hit key1=1234 failed")
+	}
+	if _, ok := q.Get("key2"); ok {
+		t.Fatalf("cache miss key2 failed")
+	}
+	if !q.Remove("key1") || q.Len() != 0 || q.Bytes() != 0 {
+		t.Fatalf("remove key1 failed")
+	}
+}
+
+func TestPromote(t *testing.T) {
+	q := New(int64(len("k1v1")*8), nil)
+	q.Add("k1", String("v1"))
+	// k1被挤出a1in，进入a1out
+	for i := 0; i < 8; i++ {
+		q.Add(fmt.Sprintf("s%d", i), String("xx"))
+	}
+	if _, ok := q.Get("k1"); ok {
+		t.Fatalf("k1 should be evicted from a1in")
+	}
+	// 再次添加时进入am，之后的扫描不会淘汰它
+	q.Add("k1", String("v1"))
+	for i := 8; i < 30; i++ {
+		q.Add(fmt.Sprintf("s%d", i), String("xx"))
+	}
+	if _, ok := q.Get("k1"); !ok {
+		t.Fatalf("k1 should stay in am")
+	}
+	if q.Bytes() > q.maxBytes {
+		t.Fatalf("cache exceeds %d bytes", q.maxBytes)
+	}
+}
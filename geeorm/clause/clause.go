@@ -19,6 +19,7 @@ const (
 	UPDATE
 	DELETE
 	COUNT
+	OFFSET
 )
 
 // Clause是一个记录有一个sql语句的抽象对象
@@ -21,6 +21,20 @@ func TestSelect(t *testing.T) {
 	}
 }
 
+func TestOffset(t *testing.T) {
+	var clause Clause
+	clause.Set(SELECT, "User", []string{"*"})
+	clause.Set(LIMIT, 10)
+	clause.Set(OFFSET, 20)
+	sql, vars := clause.Build(SELECT, WHERE, ORDERBY, LIMIT, OFFSET)
+	if sql != "SELECT * FROM User LIMIT ? OFFSET ?" {
+		t.Fatal("failed to build SQL", sql)
+	}
+	if !reflect.DeepEqual(vars, []interface{}{10, 20}) {
+		t.Fatal("failed to build SQLVars", vars)
+	}
+}
+
 func TestClause_Build(t *testing.T) {
 	t.Run("select", func(t *testing.T) {
 		TestSelect(t)
@@ -22,6 +22,7 @@ func init() {
 	generators[UPDATE] = _update
 	generators[DELETE] = _delete
 	generators[COUNT] = _count
+	generators[OFFSET] = _offset
 }
 
 func genBindVars(num int) string {
@@ -72,6 +73,12 @@ func _limit(values ...interface{}) (string, []interface{}) {
 	return "LIMIT ?", values
 }
 
+// 注意sqlite等数据库要求OFFSET与LIMIT一起使用
+func _offset(values ...interface{}) (string, []interface{}) {
+	// OFFSET $num
+	return "OFFSET ?", values
+}
+
 func _where(values ...interface{}) (string, []interface{}) {
 	// WHERE $desc
 	desc, vars := values[0], values[1:]
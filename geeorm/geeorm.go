@@ -1,6 +1,7 @@
 package geeorm
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"geektutu/geeorm/dialect"
@@ -49,6 +50,11 @@ func (engine *Engine) Close() {
 	log.Info("Close database connection successfully")
 }
 
+// Ping 检查数据库连接是否可用，可用于健康检查
+func (engine *Engine) Ping(ctx context.Context) error {
+	return engine.db.PingContext(ctx)
+}
+
 func (engine *Engine) NewSession() *session.Session {
 	return session.New(engine.db, engine.dialect)
 }
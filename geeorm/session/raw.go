@@ -35,7 +35,7 @@ func (s *Session) Exec() (sql.Result, error) {
 	defer s.Clear()
 
 	log.Info(s.sql.String(), s.sqlVars)
-	result, err := s.DB().Exec(s.sql.String(), s.sqlVars...)
+	result, err := s.DB().ExecContext(s.Context(), s.sql.String(), s.sqlVars...)
 	if err != nil {
 		log.Error(err)
 		return nil, err
@@ -47,7 +47,7 @@ func (s *Session) QueryRow() *sql.Row {
 	defer s.Clear()
 
 	log.Info(s.sql.String(), s.sqlVars)
-	return s.DB().QueryRow(s.sql.String(), s.sqlVars...)
+	return s.DB().QueryRowContext(s.Context(), s.sql.String(), s.sqlVars...)
 }
 
 func (s *Session) QueryRows() (*sql.Rows, error) {
@@ -55,7 +55,7 @@ func (s *Session) QueryRows() (*sql.Rows, error) {
 
 	log.Info(s.sql.String(), s.sqlVars)
 
-	rows, err := s.DB().Query(s.sql.String(), s.sqlVars...)
+	rows, err := s.DB().QueryContext(s.Context(), s.sql.String(), s.sqlVars...)
 	if err != nil {
 		return nil, err
 	}
@@ -1,6 +1,7 @@
 package session
 
 import (
+	"context"
 	"database/sql"
 	"geektutu/geeorm/dialect"
 	"os"
@@ -45,3 +46,22 @@ func TestSession_QueryRows(t *testing.T) {
 		t.Fatal("failed to query db", err)
 	}
 }
+
+func TestSession_WithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := NewSession().WithContext(ctx)
+	_, _ = s.Raw("DROP TABLE IF EXISTS User;").Exec()
+	if _, err := s.Raw("CREATE TABLE User(Name text);").Exec(); err != nil {
+		t.Fatal("failed to exec with context", err)
+	}
+	cancel()
+	if _, err := s.Raw("INSERT INTO User(`Name`) values (?)", "Tom").Exec(); err != context.Canceled {
+		t.Fatal("expect context.Canceled, but got", err)
+	}
+	if _, err := s.Raw("SELECT * FROM User").QueryRows(); err != context.Canceled {
+		t.Fatal("expect context.Canceled, but got", err)
+	}
+	if err := s.Begin(); err != context.Canceled {
+		t.Fatal("expect context.Canceled, but got", err)
+	}
+}
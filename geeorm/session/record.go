@@ -55,7 +55,7 @@ func (s *Session) Find(values interface{}) error {
 	table := s.Model(reflect.New(destType).Elem().Interface()).RefTable()
 
 	s.clause.Set(clause.SELECT, table.Name, table.FieldNames)
-	sql, vars := s.clause.Build(clause.SELECT, clause.WHERE, clause.ORDERBY, clause.LIMIT)
+	sql, vars := s.clause.Build(clause.SELECT, clause.WHERE, clause.ORDERBY, clause.LIMIT, clause.OFFSET)
 	rows, err := s.Raw(sql, vars...).QueryRows()
 	if err != nil {
 		return err
@@ -128,6 +128,12 @@ func (s *Session) Limit(num int) *Session {
 	return s
 }
 
+// Offset adds offset condition to clause, it should be used together with Limit
+func (s *Session) Offset(num int) *Session {
+	s.clause.Set(clause.OFFSET, num)
+	return s
+}
+
 // Where adds limit condition to clause
 func (s *Session) Where(desc string, args ...interface{}) *Session {
 	var vars []interface{}
@@ -141,6 +147,9 @@ func (s *Session) OrderBy(desc string) *Session {
 	return s
 }
 
+// ErrNotFound is returned by First when no record matches
+var ErrNotFound = errors.New("NOT FOUND")
+
 func (s *Session) First(value interface{}) error {
 	dest := reflect.Indirect(reflect.ValueOf(value))
 	destSlice := reflect.New(reflect.SliceOf(dest.Type())).Elem()
@@ -148,7 +157,7 @@ func (s *Session) First(value interface{}) error {
 		return err
 	}
 	if destSlice.Len() == 0 {
-		return errors.New("NOT FOUND")
+		return ErrNotFound
 	}
 	dest.Set(destSlice.Index(0))
 	return nil
@@ -45,6 +45,15 @@ func TestSession_Limit(t *testing.T) {
 	}
 }
 
+func TestSession_Offset(t *testing.T) {
+	s := testRecordInit(t)
+	var users []User
+	err := s.OrderBy("Age").Limit(1).Offset(1).Find(&users)
+	if err != nil || len(users) != 1 || users[0].Name != "Sam" {
+		t.Fatal("failed to query with offset condition")
+	}
+}
+
 func TestSession_Update(t *testing.T) {
 	s := testRecordInit(t)
 	affected, _ := s.Where("Name = ?", "Tom").Update("Age", 30)
@@ -1,6 +1,7 @@
 package session
 
 import (
+	"context"
 	"database/sql"
 	"geektutu/geeorm/clause"
 	"geektutu/geeorm/dialect"
@@ -29,6 +30,9 @@ type Session struct {
 
 	// 支持事务
 	tx *sql.Tx
+
+	// 语句执行时使用的context，用于取消和超时
+	ctx context.Context
 }
 
 func New(db *sql.DB, dialect dialect.Dialect) *Session {
@@ -40,4 +44,26 @@ type CommonDB interface {
 	Query(query string, args ...interface{}) (*sql.Rows, error)
 	QueryRow(query string, args ...interface{}) *sql.Row
 	Exec(query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+var _ CommonDB = (*sql.DB)(nil)
+var _ CommonDB = (*sql.Tx)(nil)
+
+// WithContext 设置session之后执行的语句（包括开启事务）使用的context，
+// context被取消或者超时时，正在执行的语句会被中断并返回错误，例如在gee的handler中：
+// s.WithContext(c).Where("Age > ?", 18).Find(&users)
+func (s *Session) WithContext(ctx context.Context) *Session {
+	s.ctx = ctx
+	return s
+}
+
+// Context 返回session使用的context，没有设置时为context.Background()
+func (s *Session) Context() context.Context {
+	if s.ctx == nil {
+		return context.Background()
+	}
+	return s.ctx
 }
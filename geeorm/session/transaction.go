@@ -4,7 +4,7 @@ import "geektutu/geeorm/log"
 
 func (s *Session) Begin() (err error) {
 	log.Info("transaction begin")
-	if s.tx, err = s.db.Begin(); err != nil {
+	if s.tx, err = s.db.BeginTx(s.Context(), nil); err != nil {
 		log.Error(err)
 		return
 	}
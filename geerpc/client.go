@@ -10,6 +10,7 @@ import (
 	"log"
 	"net"
 	"net/http"
+	"reflect"
 	"strings"
 	"sync"
 	"time"
@@ -58,6 +59,17 @@ type Client struct {
 
 var ErrShutdown error = errors.New("connection is shut down")
 
+// ErrTimeout 表示ctx在得到响应之前结束，Client.Call和Server.Call返回的超时错误都包装了它，用errors.Is判断
+var ErrTimeout = errors.New("timeout")
+
+// ServerError 是服务端处理请求时返回的错误，收到它说明连接和服务端都是正常的，
+// 调用方可以用errors.As与连接断开、超时等错误区分开
+type ServerError string
+
+func (e ServerError) Error() string {
+	return string(e)
+}
+
 func (c *Client) Close() error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -173,7 +185,7 @@ func (c *Client) Call(ctx context.Context, ServiceMethod string, args, reply int
 	select {
 	case <-ctx.Done():
 		c.removeCall(call.Seq)
-		return fmt.Errorf("call %s timeout", call.ServiceMethod)
+		return fmt.Errorf("call %s %w", call.ServiceMethod, ErrTimeout)
 	case c := <-call.Done:
 		return c.Error
 	}
@@ -214,12 +226,12 @@ func (c *Client) receive() {
 			// 服务端处理该请求发生错误，因此body响应也应该无效，我们读取body后抛弃
 			// 然后标记该call已经处理完毕，再继续后续call处理
 			//call.Error = errors.New(h.Error)
-			call.Error = fmt.Errorf(h.Error)
+			call.Error = ServerError(h.Error)
 			err = c.cc.ReadBody(nil)
 			call.done()
 		default:
 			err = c.cc.ReadBody(call.Reply)
-			log.Printf("rpc client: receive response body %d \n", *(call.Reply.(*int)))
+			log.Printf("rpc client: receive response body %v \n", reflect.Indirect(reflect.ValueOf(call.Reply)))
 			if err != nil {
 				call.Error = errors.New("reading body " + err.Error())
 			}
@@ -0,0 +1,169 @@
+// Package gateway 将geerpc的服务以HTTP/JSON的形式暴露在gee上，浏览器和curl可以直接调用：
+// curl -X POST -d '{"Num1":1,"Num2":2}' http://localhost:9999/rpc/Foo/Sum
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"geektutu/geerpc"
+	"geektutu/geerpc/xclient"
+	"geektutu/geeweb/gee"
+)
+
+// Caller 是实际发起调用的一方，*geerpc.Server（进程内调用）、*geerpc.Client和*xclient.XClient都实现了它
+type Caller interface {
+	Call(ctx context.Context, serviceMethod string, args, reply interface{}) error
+}
+
+// Gateway 根据方法表解码JSON请求、发起调用，并将reply编码为JSON返回
+type Gateway struct {
+	caller  Caller
+	methods map[string]geerpc.MethodInfo
+	server  *geerpc.Server // 进程内的服务，用于在列表中展示调用次数
+
+	// Timeout 单次调用的超时时间，为0表示只受请求本身的context限制
+	Timeout time.Duration
+}
+
+// New 为进程内的server创建网关，调用不经过网络
+func New(server *geerpc.Server) *Gateway {
+	g := &Gateway{caller: server, server: server, methods: make(map[string]geerpc.MethodInfo)}
+	for _, m := range server.Methods() {
+		g.methods[m.Service+"."+m.Method] = m
+	}
+	return g
+}
+
+// NewXClient 为远程的服务创建网关，客户端无法得知服务的方法表，
+// 因此需要提供服务的原型（与服务端注册的类型相同，一般是零值），例如NewXClient(xc, new(Foo))
+func NewXClient(xc *xclient.XClient, services ...interface{}) (*Gateway, error) {
+	return NewCaller(xc, services...)
+}
+
+// NewCaller 为任意的Caller创建网关，services的含义与NewXClient相同
+func NewCaller(caller Caller, services ...interface{}) (*Gateway, error) {
+	prototypes := geerpc.NewServer()
+	for _, svc := range services {
+		if err := prototypes.Register(svc); err != nil {
+			return nil, err
+		}
+	}
+	g := New(prototypes)
+	g.caller, g.server = caller, nil
+	return g, nil
+}
+
+// Mount 在分组下注册路由：
+// POST /:service/:method 调用方法，body为JSON编码的参数，响应为JSON编码的reply
+// GET / 列出所有可以调用的方法
+// 例如gateway.New(server).Mount(r.Group("/rpc"))，之后通过POST /rpc/Foo/Sum调用Foo.Sum
+func (g *Gateway) Mount(group *gee.RouterGroup) {
+	group.GET("/", g.list)
+	group.POST("/:service/:method", g.call)
+}
+
+// methodDesc 是方法列表中的一项
+type methodDesc struct {
+	Service   string `json:"service"`
+	Method    string `json:"method"`
+	ArgType   string `json:"arg_type"`
+	ReplyType string `json:"reply_type"`
+	Calls     uint64 `json:"calls"`
+}
+
+func (g *Gateway) list(c *gee.Context) {
+	methods := make([]methodDesc, 0, len(g.methods))
+	numCalls := make(map[string]uint64)
+	if g.server != nil {
+		for _, m := range g.server.Methods() {
+			numCalls[m.Service+"."+m.Method] = m.NumCalls
+		}
+	}
+	for key, m := range g.methods {
+		methods = append(methods, methodDesc{
+			Service:   m.Service,
+			Method:    m.Method,
+			ArgType:   m.ArgType.String(),
+			ReplyType: m.ReplyType.String(),
+			Calls:     numCalls[key],
+		})
+	}
+	sort.Slice(methods, func(i, j int) bool {
+		if methods[i].Service != methods[j].Service {
+			return methods[i].Service < methods[j].Service
+		}
+		return methods[i].Method < methods[j].Method
+	})
+	c.JSON(http.StatusOK, methods)
+}
+
+func (g *Gateway) call(c *gee.Context) {
+	serviceMethod := c.Param("service") + "." + c.Param("method")
+	m, ok := g.methods[serviceMethod]
+	if !ok {
+		renderError(c, http.StatusNotFound, "rpc method "+serviceMethod+" doesn't exist")
+		return
+	}
+
+	// 与服务端一样，ArgType可能是指针，也可能不是指针，解码时总是需要指针
+	argv := reflect.New(m.ArgType)
+	if m.ArgType.Kind() == reflect.Ptr {
+		argv.Elem().Set(reflect.New(m.ArgType.Elem()))
+	}
+	if err := json.NewDecoder(c.Req.Body).Decode(argv.Interface()); err != nil && err != io.EOF {
+		renderError(c, http.StatusBadRequest, "invalid json body: "+err.Error())
+		return
+	}
+	replyv := reflect.New(m.ReplyType.Elem())
+	switch m.ReplyType.Elem().Kind() {
+	case reflect.Map:
+		replyv.Elem().Set(reflect.MakeMap(m.ReplyType.Elem()))
+	case reflect.Slice:
+		replyv.Elem().Set(reflect.MakeSlice(m.ReplyType.Elem(), 0, 0))
+	}
+
+	var ctx context.Context = c
+	if g.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.Timeout)
+		defer cancel()
+	}
+	if err := g.caller.Call(ctx, serviceMethod, argv.Elem().Interface(), replyv.Interface()); err != nil {
+		renderError(c, statusOf(ctx, err), err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, replyv.Elem().Interface())
+}
+
+// statusOf 将调用的错误映射为状态码。超时和没有可用服务端使用geerpc和xclient导出的错误判断，
+// 方法不存在的错误跨网络之后只剩下错误信息，因此根据信息判断
+func statusOf(ctx context.Context, err error) int {
+	msg := err.Error()
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded), errors.Is(err, geerpc.ErrTimeout):
+		return http.StatusGatewayTimeout
+	case strings.HasSuffix(msg, "doesn't exist"), strings.Contains(msg, "ill-formed"):
+		return http.StatusNotFound
+	case errors.Is(err, geerpc.ErrShutdown), errors.Is(err, xclient.ErrNoServers):
+		return http.StatusServiceUnavailable
+	}
+	var sc interface{ StatusCode() int }
+	if errors.As(err, &sc) {
+		return sc.StatusCode()
+	}
+	// 方法本身返回的错误
+	return http.StatusInternalServerError
+}
+
+// renderError 与gee.Context.Error的格式相同，但总是返回错误信息：方法返回的错误就是接口的一部分
+func renderError(c *gee.Context, code int, message string) {
+	c.JSON(code, gee.Obj{"error": message})
+}
@@ -0,0 +1,125 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"geektutu/geerpc"
+	"geektutu/geerpc/xclient"
+	"geektutu/geeweb/gee"
+)
+
+type Args struct{ Num1, Num2 int }
+
+type Reply struct {
+	Sum  int
+	Tags []string
+}
+
+type Foo int
+
+func (f Foo) Sum(args Args, reply *Reply) error {
+	reply.Sum = args.Num1 + args.Num2
+	return nil
+}
+
+func (f Foo) Div(args *Args, reply *int) error {
+	if args.Num2 == 0 {
+		return errors.New("divide by zero")
+	}
+	*reply = args.Num1 / args.Num2
+	return nil
+}
+
+// Retry 返回的错误信息中含有timeout，但它是方法本身的错误
+func (f Foo) Retry(args int, reply *int) error {
+	return errors.New("upstream timeout, retry later")
+}
+
+func (f Foo) Sleep(args int, reply *int) error {
+	time.Sleep(time.Duration(args) * time.Millisecond)
+	*reply = args
+	return nil
+}
+
+func testGateway(t *testing.T, g *Gateway) {
+	g.Timeout = 100 * time.Millisecond
+	r := gee.New()
+	g.Mount(r.Group("/rpc"))
+
+	testCases := []struct {
+		url  string
+		body string
+		code int
+		want string
+	}{
+		{"/rpc/Foo/Sum", `{"Num1":1,"Num2":2}`, 200, `{"Sum":3,"Tags":null}`},
+		{"/rpc/Foo/Div", `{"Num1":6,"Num2":3}`, 200, `2`},
+		{"/rpc/Foo/Div", `{"Num1":6,"Num2":0}`, 500, `{"error":"divide by zero"}`},
+		{"/rpc/Foo/Div", `{"Num1":`, 400, `"error":"invalid json body`},
+		{"/rpc/Foo/Sleep", `500`, 504, `timeout`},
+		{"/rpc/Foo/Retry", `1`, 500, `{"error":"upstream timeout, retry later"}`},
+		{"/rpc/Foo/Mul", `{}`, 404, `{"error":"rpc method Foo.Mul doesn't exist"}`},
+	}
+	for _, tc := range testCases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("POST", tc.url, strings.NewReader(tc.body)))
+		if w.Code != tc.code || !strings.Contains(w.Body.String(), tc.want) {
+			t.Errorf("POST %s %s: got code=%d body=%q, want code=%d body containing %q",
+				tc.url, tc.body, w.Code, w.Body.String(), tc.code, tc.want)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/rpc/", nil))
+	var methods []methodDesc
+	if err := json.Unmarshal(w.Body.Bytes(), &methods); err != nil || len(methods) != 4 {
+		t.Fatalf("GET /rpc/: got %q", w.Body.String())
+	}
+	if m := methods[0]; m.Service != "Foo" || m.Method != "Div" || m.ArgType != "*gateway.Args" || m.ReplyType != "*int" {
+		t.Errorf("unexpected method %+v", m)
+	}
+}
+
+func TestGateway(t *testing.T) {
+	server := geerpc.NewServer()
+	var foo Foo
+	if err := server.Register(&foo); err != nil {
+		t.Fatal(err)
+	}
+	testGateway(t, New(server))
+}
+
+func TestGatewayXClient(t *testing.T) {
+	server := geerpc.NewServer()
+	var foo Foo
+	_ = server.Register(&foo)
+	l, _ := net.Listen("tcp", "127.0.0.1:0")
+	defer l.Close()
+	go server.Accept(l)
+
+	d := xclient.NewMultiServersDiscovery([]string{"tcp@" + l.Addr().String()})
+	xc := xclient.NewXClient(d, xclient.RandomMode, nil)
+	defer func() { _ = xc.Close() }()
+	g, err := NewXClient(xc, new(Foo))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testGateway(t, g)
+
+	// 没有可用的服务端
+	_ = d.Update(nil)
+	w := httptest.NewRecorder()
+	r := gee.New()
+	g.Mount(r.Group("/rpc"))
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/rpc/Foo/Sum", strings.NewReader(`{}`)))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("no servers should be 503, got %d %q", w.Code, w.Body.String())
+	}
+}
@@ -1,13 +1,17 @@
 package geerpc
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
 	"net"
 	"net/http"
 	"reflect"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -79,6 +83,61 @@ func (srv *Server) findServiceMethod(ServiceMethod string) (svc *service, mtype
 	return
 }
 
+// MethodInfo 描述一个已注册的方法，供网关等需要在进程内了解服务信息的场景使用
+type MethodInfo struct {
+	Service   string
+	Method    string
+	ArgType   reflect.Type
+	ReplyType reflect.Type
+	NumCalls  uint64
+}
+
+// Methods 返回所有已注册的方法，按服务名和方法名排序
+func (srv *Server) Methods() []MethodInfo {
+	var methods []MethodInfo
+	srv.serviceMap.Range(func(namei, svci interface{}) bool {
+		svc := svci.(*service)
+		for name, m := range svc.methods {
+			methods = append(methods, MethodInfo{
+				Service:   svc.name,
+				Method:    name,
+				ArgType:   m.ArgType,
+				ReplyType: m.ReplyType,
+				NumCalls:  m.NumCalls(),
+			})
+		}
+		return true
+	})
+	sort.Slice(methods, func(i, j int) bool {
+		if methods[i].Service != methods[j].Service {
+			return methods[i].Service < methods[j].Service
+		}
+		return methods[i].Method < methods[j].Method
+	})
+	return methods
+}
+
+// Call 在进程内直接调用已注册的方法，不经过编解码和网络，参数与Client.Call相同：
+// argv的类型必须是方法的ArgType，replyv必须是ReplyType类型的指针。
+// ctx结束时立即返回包装了ErrTimeout的错误。注册的方法不接收ctx，没有办法中断，
+// 执行方法的goroutine会继续运行到方法返回为止，结果被丢弃，在此之前它仍然可能写入replyv
+func (srv *Server) Call(ctx context.Context, serviceMethod string, argv, replyv interface{}) error {
+	svc, mtype, err := srv.findServiceMethod(serviceMethod)
+	if err != nil {
+		return err
+	}
+	called := make(chan error, 1)
+	go func() {
+		called <- svc.call(mtype, reflect.ValueOf(argv), reflect.ValueOf(replyv))
+	}()
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("call %s %w", serviceMethod, ErrTimeout)
+	case err := <-called:
+		return err
+	}
+}
+
 func Register(service interface{}) error {
 	return DefaultServer.Register(service)
 }
@@ -130,10 +189,27 @@ func (srv *Server) ServeConn(conn io.ReadWriteCloser) {
 	}
 	log.Println("rpc server: codec is ", opt.CodecType)
 	log.Println("rpc server: preparing for getting request...")
+	// 客户端发送option之后紧接着就会发送请求，jsonDec可能已经把请求的一部分读进了它的buffer，
+	// 因此编解码器需要先读完jsonDec.Buffered()中剩余的数据（去掉json.Encoder在末尾添加的换行），再继续从连接中读取
+	if c, ok := conn.(net.Conn); ok {
+		buffered, _ := ioutil.ReadAll(jsonDec.Buffered())
+		buffered = bytes.TrimPrefix(buffered, []byte{'\n'})
+		conn = &bufferedConn{Conn: c, r: io.MultiReader(bytes.NewReader(buffered), c)}
+	}
 	// 最后使用编解码器解析请求并给出响应
 	srv.ServeCodec(codecFunc(conn), opt.HandleTimeout)
 }
 
+// bufferedConn 从r读取数据的net.Conn
+type bufferedConn struct {
+	net.Conn
+	r io.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
+
 // 无效请求给回的响应，在请求有问题时返回，注意提前设置Header中的Error参数
 var invalidRequestResponse = struct{}{}
 
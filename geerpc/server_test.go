@@ -0,0 +1,88 @@
+package geerpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"geektutu/geerpc/codec"
+)
+
+type bufferRWC struct {
+	io.Reader
+	io.Writer
+}
+
+func (bufferRWC) Close() error { return nil }
+
+// option和第一个请求在同一次Write中发出时，服务端也必须能读到这个请求
+func TestServer_ServeConnSingleWrite(t *testing.T) {
+	srv := NewServer()
+	_ = srv.Register(new(Foo))
+	l, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go srv.Accept(l)
+
+	var buf bytes.Buffer
+	_ = json.NewEncoder(&buf).Encode(&DefaultOption)
+	cc := codec.NewGobCodec(bufferRWC{Reader: &bytes.Buffer{}, Writer: &buf})
+	h := &codec.Header{ServiceMethod: "Foo.Sum", Seq: 1}
+	if err := cc.Write(h, &Args{Num1: 1, Num2: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(3 * time.Second))
+	if _, err := conn.Write(buf.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+
+	resp := codec.NewGobCodec(conn)
+	var rh codec.Header
+	if err := resp.ReadHeader(&rh); err != nil {
+		t.Fatalf("failed to read response header: %v", err)
+	}
+	var reply int
+	if err := resp.ReadBody(&reply); err != nil {
+		t.Fatal(err)
+	}
+	_assert(rh.Error == "" && rh.Seq == 1, "unexpected response header %+v", rh)
+	_assert(reply == 3, "expect 3, but got %d", reply)
+}
+
+func TestServerError(t *testing.T) {
+	srv := NewServer()
+	_ = srv.Register(new(Foo))
+	l, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go srv.Accept(l)
+
+	client, err := Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	var reply int
+	err = client.Call(context.Background(), "Foo.None", Args{}, &reply)
+	var serverErr ServerError
+	_assert(errors.As(err, &serverErr), "expect a ServerError, but got %v", err)
+
+	_ = client.Close()
+	err = client.Call(context.Background(), "Foo.Sum", Args{Num1: 1, Num2: 2}, &reply)
+	_assert(err != nil && !errors.As(err, &serverErr), "closed connection should not be a ServerError, but got %v", err)
+}
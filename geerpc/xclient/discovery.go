@@ -12,6 +12,9 @@ import (
 	"time"
 )
 
+// ErrNoServers 表示Discovery中没有可用的服务端
+var ErrNoServers = errors.New("no servers")
+
 type SelectMode int
 
 const (
@@ -71,7 +74,7 @@ func (msd *MultiServersDiscovery) Get(mode SelectMode) (string, error) {
 	defer msd.mu.Unlock()
 	mod := len(msd.servers)
 	if mod == 0 {
-		return "", errors.New("no servers")
+		return "", ErrNoServers
 	}
 	switch mode {
 	case RandomMode:
@@ -105,6 +105,7 @@ func (xc *XClient) Broadcast(ctx context.Context, serviceMethod string, argv, re
 	replyDone := replyv == nil
 
 	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
 	for _, rpcAddr := range servers {
 		wg.Add(1)
@@ -1,9 +1,15 @@
 package gee
 
 import (
+	"context"
 	"encoding/json"
+	"encoding/xml"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
 // 这是一个通用的object定义，可以用于方便的构建一个对象
@@ -25,9 +31,22 @@ type Context struct {
 	// 记录当前中间件（待施加的处理函数列表）列表，包括路由处理函数
 	handlers []HandlerFunc
 	index    int
+	// 调用过Abort，处理函数全部正常执行完时index同样会走到末尾，因此需要单独记录
+	aborted bool
 
 	// 增加到engine的访问，获取其中的htmlTemplates
 	engine *Engine
+
+	// 请求范围内的键值对，用于在中间件和handler之间传递数据，例如csrf token
+	mu   sync.RWMutex
+	keys map[string]interface{}
+
+	// 经过可信代理解析得到的客户端信息，见ClientIP
+	client *forwardedHop
+
+	// 请求处理完成时关闭，用于结束OnDisconnect启动的goroutine
+	finished     chan struct{}
+	disconnected bool
 }
 
 func newContext(w http.ResponseWriter, req *http.Request) *Context {
@@ -45,6 +64,105 @@ func (c *Context) Param(key string) string {
 	return value
 }
 
+// Context实现了context.Context，Deadline、Done和Err委托给请求的context，
+// 因此可以直接传给geeorm和geerpc：客户端断开连接时，数据库查询和rpc调用也会随之取消
+// s.WithContext(c).Find(&users)
+// client.Call(c, "Foo.Sum", args, &reply)
+var _ context.Context = (*Context)(nil)
+
+func (c *Context) Deadline() (deadline time.Time, ok bool) {
+	return c.Req.Context().Deadline()
+}
+
+func (c *Context) Done() <-chan struct{} {
+	return c.Req.Context().Done()
+}
+
+func (c *Context) Err() error {
+	return c.Req.Context().Err()
+}
+
+// Value 对于字符串的key，先查找通过Set保存的值，其余的交给请求的context
+func (c *Context) Value(key interface{}) interface{} {
+	if k, ok := key.(string); ok {
+		if value, exists := c.Get(k); exists {
+			return value
+		}
+	}
+	return c.Req.Context().Value(key)
+}
+
+// OnDisconnect 注册客户端断开连接时的回调，只在请求处理完成之前断开时调用，fn在单独的goroutine中执行。
+// 耗时的handler可以借此停止工作，或者直接使用c.Done()
+func (c *Context) OnDisconnect(fn func()) {
+	c.mu.Lock()
+	if c.finished == nil {
+		c.finished = make(chan struct{})
+	}
+	finished := c.finished
+	c.mu.Unlock()
+
+	go func() {
+		select {
+		case <-c.Req.Context().Done():
+		case <-finished:
+		}
+		if c.Req.Context().Err() != context.Canceled {
+			return
+		}
+		// 请求处理完成之后，net/http同样会取消请求的context，这种情况不算断开连接。
+		// finish在持有锁时记录结果并关闭finished，因此两者要在同一次加锁中读取
+		c.mu.RLock()
+		disconnected := true
+		select {
+		case <-finished:
+			disconnected = c.disconnected
+		default:
+		}
+		c.mu.RUnlock()
+		if disconnected {
+			fn()
+		}
+	}()
+}
+
+// finish 在请求处理完成之后调用，记录完成之前客户端是否已经断开
+func (c *Context) finish() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.finished != nil {
+		c.disconnected = c.Req.Context().Err() == context.Canceled
+		close(c.finished)
+	}
+}
+
+// setPath 改写请求路径，之后的路由和处理都使用新的路径
+func (c *Context) setPath(p string) {
+	req := *c.Req
+	u := *req.URL
+	u.Path, u.RawPath = p, ""
+	req.URL = &u
+	c.Req, c.Path = &req, p
+}
+
+// Set 在Context中保存一个键值对
+func (c *Context) Set(key string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.keys == nil {
+		c.keys = make(map[string]interface{})
+	}
+	c.keys[key] = value
+}
+
+// Get 获取通过Set保存的值
+func (c *Context) Get(key string) (value interface{}, exists bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	value, exists = c.keys[key]
+	return
+}
+
 func (c *Context) PostForm(key string) string {
 	return c.Req.FormValue(key)
 }
@@ -53,6 +171,46 @@ func (c *Context) Query(key string) string {
 	return c.Req.URL.Query().Get(key)
 }
 
+// ParamInt 将路径参数转换为int，参数不存在或者不是合法的整数时返回error
+// 一般与约束一起使用，例如/user/:id{int}，此时转换不会失败（溢出除外）
+func (c *Context) ParamInt(key string) (int, error) {
+	value, ok := c.Params[key]
+	if !ok {
+		return 0, fmt.Errorf("param %s not found", key)
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("param %s: %v", key, err)
+	}
+	return i, nil
+}
+
+// ParamUint64 将路径参数转换为uint64，参数不存在或者不是合法的无符号整数时返回error
+func (c *Context) ParamUint64(key string) (uint64, error) {
+	value, ok := c.Params[key]
+	if !ok {
+		return 0, fmt.Errorf("param %s not found", key)
+	}
+	u, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("param %s: %v", key, err)
+	}
+	return u, nil
+}
+
+// QueryInt 获取int类型的查询参数，参数不存在时返回缺省值def，不是合法的整数时返回def和error
+func (c *Context) QueryInt(key string, def int) (int, error) {
+	value := c.Query(key)
+	if value == "" {
+		return def, nil
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return def, fmt.Errorf("query %s: %v", key, err)
+	}
+	return i, nil
+}
+
 func (c *Context) Status(code int) {
 	c.StatusCode = code
 	c.Writer.WriteHeader(code)
@@ -79,6 +237,31 @@ func (c *Context) JSON(code int, obj interface{}) {
 	}
 }
 
+// Redirect 重定向到location，code一般为301、302、307或308
+func (c *Context) Redirect(code int, location string) {
+	c.StatusCode = code
+	http.Redirect(c.Writer, c.Req, location, code)
+}
+
+func (c *Context) XML(code int, obj interface{}) {
+	c.SetHeader("Content-Type", "application/xml")
+	c.Status(code)
+	if err := xml.NewEncoder(c.Writer).Encode(obj); err != nil {
+		http.Error(c.Writer, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// Negotiate 根据请求的Accept头选择响应格式，支持json和xml，缺省为json
+func (c *Context) Negotiate(code int, obj interface{}) {
+	accept := c.Req.Header.Get("Accept")
+	if (strings.Contains(accept, "application/xml") || strings.Contains(accept, "text/xml")) &&
+		!strings.Contains(accept, "application/json") {
+		c.XML(code, obj)
+		return
+	}
+	c.JSON(code, obj)
+}
+
 func (c *Context) Data(code int, data []byte) {
 	c.Status(code)
 	c.Writer.Write(data)
@@ -110,3 +293,15 @@ func (c *Context) Next() {
 		c.handlers[c.index](c)
 	}
 }
+
+// Abort 跳过后续的中间件和路由处理函数，例如缓存命中或者校验失败时，中间件已经写好了响应
+// 注意Next中的循环并不会因为中间件没有调用c.Next()而停止，因此需要显式调用Abort
+func (c *Context) Abort() {
+	c.index = len(c.handlers)
+	c.aborted = true
+}
+
+// IsAborted 判断是否已经调用了Abort
+func (c *Context) IsAborted() bool {
+	return c.aborted
+}
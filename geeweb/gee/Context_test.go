@@ -0,0 +1,129 @@
+package gee
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTypedAccessors(t *testing.T) {
+	c := newContext(httptest.NewRecorder(), httptest.NewRequest("GET", "/user/42?page=3&size=x", nil))
+	c.Params = map[string]string{"id": "42", "name": "tom"}
+
+	if id, err := c.ParamInt("id"); err != nil || id != 42 {
+		t.Errorf("ParamInt(id) = %d, %v, but we want 42", id, err)
+	}
+	if _, err := c.ParamInt("name"); err == nil {
+		t.Errorf("ParamInt(name) should fail")
+	}
+	if _, err := c.ParamUint64("none"); err == nil {
+		t.Errorf("ParamUint64(none) should fail")
+	}
+	if id, err := c.ParamUint64("id"); err != nil || id != 42 {
+		t.Errorf("ParamUint64(id) = %d, %v, but we want 42", id, err)
+	}
+	if page, err := c.QueryInt("page", 1); err != nil || page != 3 {
+		t.Errorf("QueryInt(page) = %d, %v, but we want 3", page, err)
+	}
+	if limit, err := c.QueryInt("limit", 10); err != nil || limit != 10 {
+		t.Errorf("QueryInt(limit) = %d, %v, but we want default 10", limit, err)
+	}
+	if size, err := c.QueryInt("size", 20); err == nil || size != 20 {
+		t.Errorf("QueryInt(size) = %d, %v, but we want default 20 and an error", size, err)
+	}
+}
+
+type ctxKey struct{}
+
+func TestContextValue(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req = req.WithContext(context.WithValue(req.Context(), ctxKey{}, "request"))
+	c := newContext(httptest.NewRecorder(), req)
+	c.Set("user", "geektutu")
+
+	var ctx context.Context = c
+	if v := ctx.Value("user"); v != "geektutu" {
+		t.Errorf("Value(user) = %v, but we want geektutu", v)
+	}
+	if v := ctx.Value(ctxKey{}); v != "request" {
+		t.Errorf("Value(ctxKey{}) = %v, but we want request", v)
+	}
+	if ctx.Err() != nil {
+		t.Errorf("Err() = %v, but we want nil", ctx.Err())
+	}
+}
+
+func TestOnDisconnect(t *testing.T) {
+	disconnected := make(chan struct{})
+	stopped := make(chan error, 1)
+	finished := make(chan struct{})
+	r := New()
+	r.GET("/slow", func(c *Context) {
+		c.OnDisconnect(func() { close(disconnected) })
+		select {
+		case <-c.Done():
+			stopped <- c.Err()
+		case <-time.After(5 * time.Second):
+			stopped <- nil
+		}
+	})
+	r.GET("/fast", func(c *Context) {
+		c.OnDisconnect(func() { close(finished) })
+		c.Stringf(http.StatusOK, "ok")
+	})
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequest("GET", ts.URL+"/slow", nil)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+	if _, err := http.DefaultClient.Do(req.WithContext(ctx)); err == nil {
+		t.Fatal("request should be canceled")
+	}
+	select {
+	case <-disconnected:
+	case <-time.After(time.Second):
+		t.Fatal("OnDisconnect callback should be called")
+	}
+	if err := <-stopped; err != context.Canceled {
+		t.Errorf("handler should be stopped by c.Done(), got err=%v", err)
+	}
+
+	resp, err := http.Get(ts.URL + "/fast")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	select {
+	case <-finished:
+		t.Error("OnDisconnect callback should not be called after the request finished")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestIsAborted(t *testing.T) {
+	r := New()
+	var aborted bool
+	r.Use(func(c *Context) {
+		c.Next()
+		aborted = c.IsAborted()
+	})
+	r.GET("/ok", func(c *Context) { c.Stringf(http.StatusOK, "ok") })
+	r.GET("/abort", func(c *Context) {
+		c.Stringf(http.StatusForbidden, "forbidden")
+		c.Abort()
+	})
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/ok", nil))
+	if aborted {
+		t.Errorf("IsAborted should be false after the chain finishes normally")
+	}
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/abort", nil))
+	if !aborted {
+		t.Errorf("IsAborted should be true after Abort")
+	}
+}
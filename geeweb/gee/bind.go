@@ -0,0 +1,210 @@
+package gee
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Validator 由输入类型实现，绑定之后调用，返回错误时响应400
+type Validator interface {
+	Validate() error
+}
+
+// Bind 将请求绑定到obj，obj必须是指向结构体的指针：
+// 先根据Content-Type解析body（json、xml或者表单，表单字段通过form标签指定），
+// 然后用path标签绑定路径参数，query标签绑定查询参数，后者会覆盖body中的同名字段。
+// 绑定失败返回状态码为400的HTTPError；obj实现了Validator时，还会调用Validate进行校验
+//
+//	type ListUsers struct {
+//		Group string `path:"group"`
+//		Page  int    `query:"page"`
+//	}
+func (c *Context) Bind(obj interface{}) error {
+	v := reflect.ValueOf(obj)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("gee: Bind needs a pointer to struct, but got %T", obj)
+	}
+	if err := c.bindBody(obj); err != nil {
+		return NewHTTPError(http.StatusBadRequest, "bind body: %v", err)
+	}
+	query := c.Req.URL.Query()
+	err := bindFields(v.Elem(), func(field reflect.StructField) (string, bool) {
+		if name, ok := field.Tag.Lookup("path"); ok {
+			value, exists := c.Params[name]
+			return value, exists
+		}
+		if name, ok := field.Tag.Lookup("query"); ok {
+			if _, exists := query[name]; exists {
+				return query.Get(name), true
+			}
+		}
+		return "", false
+	})
+	if err != nil {
+		return NewHTTPError(http.StatusBadRequest, "%v", err)
+	}
+	if validator, ok := obj.(Validator); ok {
+		if err := validator.Validate(); err != nil {
+			if _, ok := err.(statusCoder); ok {
+				return err
+			}
+			return NewHTTPError(http.StatusBadRequest, "%v", err)
+		}
+	}
+	return nil
+}
+
+func (c *Context) bindBody(obj interface{}) error {
+	if c.Req.Body == nil || c.Req.Body == http.NoBody || c.Req.ContentLength == 0 {
+		return nil
+	}
+	contentType := c.Req.Header.Get("Content-Type")
+	switch {
+	case strings.HasPrefix(contentType, "application/json"):
+		return json.NewDecoder(c.Req.Body).Decode(obj)
+	case strings.HasPrefix(contentType, "application/xml"), strings.HasPrefix(contentType, "text/xml"):
+		return xml.NewDecoder(c.Req.Body).Decode(obj)
+	case strings.HasPrefix(contentType, "application/x-www-form-urlencoded"), strings.HasPrefix(contentType, "multipart/form-data"):
+		if err := c.Req.ParseMultipartForm(32 << 20); err != nil && err != http.ErrNotMultipart {
+			return err
+		}
+		return bindFields(reflect.ValueOf(obj).Elem(), func(field reflect.StructField) (string, bool) {
+			if name, ok := field.Tag.Lookup("form"); ok {
+				if _, exists := c.Req.PostForm[name]; exists {
+					return c.Req.PostForm.Get(name), true
+				}
+			}
+			return "", false
+		})
+	}
+	return fmt.Errorf("unsupported content type %q", contentType)
+}
+
+// bindFields 遍历结构体的导出字段，lookup返回字段对应的字符串值
+func bindFields(v reflect.Value, lookup func(reflect.StructField) (string, bool)) error {
+	typ := v.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		value, ok := lookup(field)
+		if !ok {
+			continue
+		}
+		if err := setField(v.Field(i), value); err != nil {
+			return fmt.Errorf("field %s: %v", field.Name, err)
+		}
+	}
+	return nil
+}
+
+// setField 将字符串转换为字段的类型并赋值
+func setField(field reflect.Value, value string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Ptr:
+		elem := reflect.New(field.Type().Elem())
+		if err := setField(elem.Elem(), value); err != nil {
+			return err
+		}
+		field.Set(elem)
+	default:
+		return fmt.Errorf("unsupported kind %s", field.Kind())
+	}
+	return nil
+}
+
+var (
+	contextType = reflect.TypeOf((*Context)(nil))
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// Typed 通过反射将类型化的函数转换为HandlerFunc，支持以下形式：
+// func(c *gee.Context, in *In) (*Out, error)
+// func(c *gee.Context, in *In) error
+// func(c *gee.Context) (*Out, error)
+// 输入通过c.Bind绑定，输出按照Accept协商的格式（json或xml）返回，输出为nil时返回204。
+// 返回的错误通过c.Error映射为状态码，例如return nil, gee.NewHTTPError(404, "user %d not found", id)
+//
+// r.POST("/users", gee.Typed(func(c *gee.Context, in *CreateUser) (*User, error) {...}))
+func Typed(fn interface{}) HandlerFunc {
+	fv := reflect.ValueOf(fn)
+	ft := fv.Type()
+	if ft.Kind() != reflect.Func {
+		panic(fmt.Sprintf("gee: Typed needs a function, but got %s", ft))
+	}
+	if ft.NumIn() < 1 || ft.NumIn() > 2 || ft.In(0) != contextType {
+		panic(fmt.Sprintf("gee: Typed function %s should be func(*gee.Context[, *In])", ft))
+	}
+	var inType reflect.Type
+	if ft.NumIn() == 2 {
+		inType = ft.In(1)
+		if inType.Kind() != reflect.Ptr || inType.Elem().Kind() != reflect.Struct {
+			panic(fmt.Sprintf("gee: input of Typed function %s should be a pointer to struct", ft))
+		}
+	}
+	if ft.NumOut() < 1 || ft.NumOut() > 2 || ft.Out(ft.NumOut()-1) != errorType {
+		panic(fmt.Sprintf("gee: Typed function %s should return ([*Out, ]error)", ft))
+	}
+
+	return func(c *Context) {
+		args := []reflect.Value{reflect.ValueOf(c)}
+		if inType != nil {
+			in := reflect.New(inType.Elem())
+			if err := c.Bind(in.Interface()); err != nil {
+				c.Error(err)
+				return
+			}
+			args = append(args, in)
+		}
+		results := fv.Call(args)
+		if err, _ := results[len(results)-1].Interface().(error); err != nil {
+			c.Error(err)
+			return
+		}
+		if len(results) == 1 || isNil(results[0]) {
+			c.Status(http.StatusNoContent)
+			return
+		}
+		c.Negotiate(http.StatusOK, results[0].Interface())
+	}
+}
+
+func isNil(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+		return v.IsNil()
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package gee
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type createUser struct {
+	Group string `json:"-" path:"group"`
+	Name  string `json:"name"`
+	Age   int    `json:"age" query:"age"`
+}
+
+func (in *createUser) Validate() error {
+	if in.Name == "" {
+		return errors.New("name is required")
+	}
+	return nil
+}
+
+type user struct {
+	ID    int    `json:"id" xml:"id"`
+	Group string `json:"group" xml:"group"`
+	Name  string `json:"name" xml:"name"`
+	Age   int    `json:"age" xml:"age"`
+}
+
+func newTypedEngine() *Engine {
+	r := New()
+	r.POST("/groups/:group/users", Typed(func(c *Context, in *createUser) (*user, error) {
+		if in.Name == "root" {
+			return nil, NewHTTPError(http.StatusConflict, "user %s exists", in.Name)
+		}
+		if in.Name == "panic" {
+			return nil, errors.New("database is down")
+		}
+		return &user{ID: 1, Group: in.Group, Name: in.Name, Age: in.Age}, nil
+	}))
+	r.POST("/ping", Typed(func(c *Context) (*user, error) { return nil, nil }))
+	return r
+}
+
+func TestTyped(t *testing.T) {
+	r := newTypedEngine()
+	testCases := []struct {
+		desc   string
+		url    string
+		body   string
+		accept string
+		code   int
+		want   string
+	}{
+		{"bind path, query and body", "/groups/admin/users?age=18", `{"name":"geektutu","age":10}`, "",
+			http.StatusOK, `{"id":1,"group":"admin","name":"geektutu","age":18}`},
+		{"negotiate xml", "/groups/admin/users", `{"name":"geektutu","age":10}`, "application/xml",
+			http.StatusOK, `<user><id>1</id><group>admin</group><name>geektutu</name><age>10</age></user>`},
+		{"invalid body", "/groups/admin/users", `{"name":`, "", http.StatusBadRequest, `"error":"bind body`},
+		{"invalid query", "/groups/admin/users?age=x", `{"name":"geektutu"}`, "", http.StatusBadRequest, `"error":"field Age`},
+		{"validate", "/groups/admin/users", `{}`, "", http.StatusBadRequest, `{"error":"name is required"}`},
+		{"http error", "/groups/admin/users", `{"name":"root"}`, "", http.StatusConflict, `{"error":"user root exists"}`},
+		{"internal error", "/groups/admin/users", `{"name":"panic"}`, "application/xml",
+			http.StatusInternalServerError, `<error><message>Internal Server Error</message></error>`},
+		{"no content", "/ping", "", "", http.StatusNoContent, ""},
+	}
+	for _, tc := range testCases {
+		req := httptest.NewRequest("POST", tc.url, strings.NewReader(tc.body))
+		if tc.body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if tc.accept != "" {
+			req.Header.Set("Accept", tc.accept)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.code || !strings.Contains(w.Body.String(), tc.want) {
+			t.Errorf("%s: got code=%d body=%q, want code=%d body containing %q", tc.desc, w.Code, w.Body.String(), tc.code, tc.want)
+		}
+	}
+}
+
+func TestTypedInvalidSignature(t *testing.T) {
+	for _, fn := range []interface{}{
+		func(c *Context, in createUser) error { return nil },
+		func(in *createUser) error { return nil },
+		func(c *Context, in *createUser) *user { return nil },
+		"not a function",
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Typed(%T) should panic", fn)
+				}
+			}()
+			Typed(fn)
+		}()
+	}
+}
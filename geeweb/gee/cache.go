@@ -0,0 +1,183 @@
+package gee
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"geektutu/geecache"
+)
+
+// cachedResponse 是缓存在geecache中的响应，以json编码后作为ByteView保存
+type cachedResponse struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+func (resp *cachedResponse) write(c *Context) {
+	for k, vv := range resp.Header {
+		for _, v := range vv {
+			c.Writer.Header().Add(k, v)
+		}
+	}
+	status := resp.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	c.Status(status)
+	c.Writer.Write(resp.Body)
+}
+
+// cacheable 判断响应能否缓存：只缓存2xx，且不带Set-Cookie、no-store和private
+func (resp *cachedResponse) cacheable() bool {
+	cacheControl := resp.Header.Get("Cache-Control")
+	return resp.Status >= 200 && resp.Status < 300 && resp.Header.Get("Set-Cookie") == "" &&
+		!strings.Contains(cacheControl, "no-store") && !strings.Contains(cacheControl, "private")
+}
+
+// errCacheMiss 由Group的Getter返回，表示响应还没有缓存，需要由收到请求的节点自己执行handler
+var errCacheMiss = errors.New("gee: response not cached")
+
+// responseRecorder 记录handler写入的响应
+type responseRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *responseRecorder) WriteHeader(code int) {
+	if r.status == 0 {
+		r.status = code
+	}
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.body.Write(b)
+}
+
+// response 返回记录的响应，handler没有写入任何内容时状态码为200
+func (r *responseRecorder) response() *cachedResponse {
+	status := r.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	return &cachedResponse{Status: status, Header: r.header, Body: r.body.Bytes()}
+}
+
+// CacheOption 是响应缓存的配置
+type CacheOption struct {
+	// VaryHeaders 参与计算缓存key的请求头，例如Accept-Language，不同取值的响应分别缓存
+	VaryHeaders []string
+}
+
+type responseCache struct {
+	group *geecache.Group
+	vary  []string
+}
+
+// Cache 返回响应缓存中间件，GET请求的成功响应（状态码、响应头和body）缓存在名为name的geecache.Group中，
+// key为方法+规范化的URL（+VaryHeaders的取值），命中时直接返回缓存的响应，不再执行handler。
+// 请求带有Cache-Control: no-cache时跳过缓存；带有Authorization或Cookie的请求可能得到因人而异的响应，
+// 除非对应的请求头在VaryHeaders中，否则同样跳过缓存。
+//
+// 缓存不命中时，由收到请求的节点用原始的请求执行后续的中间件和handler，记录响应后写入Group，
+// Cookie、Authorization等请求头对handler仍然有效，带有Set-Cookie或private的响应不会被缓存。
+// 为该Group注册PeerPicker后，响应写入key所在的节点，多个gee节点就能共享一个分布式的响应缓存：
+// r.Use(gee.Cache("pages", 64<<20, nil))
+// geecache.GetGroup("pages").RegisterPeerPicker(pool)
+func Cache(name string, cacheBytes int64, opt *CacheOption) HandlerFunc {
+	rc := &responseCache{}
+	if opt != nil {
+		for _, h := range opt.VaryHeaders {
+			rc.vary = append(rc.vary, http.CanonicalHeaderKey(h))
+		}
+		sort.Strings(rc.vary)
+	}
+	rc.group = geecache.NewGroup(name, cacheBytes, geecache.GetterFunc(func(string) ([]byte, error) {
+		return nil, errCacheMiss
+	}))
+	return rc.serve
+}
+
+func (rc *responseCache) serve(c *Context) {
+	if c.Method != http.MethodGet || strings.Contains(c.Req.Header.Get("Cache-Control"), "no-cache") ||
+		rc.private(c.Req) {
+		c.Next()
+		return
+	}
+
+	key := rc.key(c)
+	if view, err := rc.group.Get(key); err == nil {
+		var resp cachedResponse
+		if err = json.Unmarshal(view.ByteSlice(), &resp); err == nil {
+			resp.write(c)
+			c.Abort()
+			return
+		}
+		log.Printf("gee: response cache %s: %v", c.Req.RequestURI, err)
+	}
+
+	// 未命中，把c.Writer替换为responseRecorder，执行handler后再写给客户端
+	w := c.Writer
+	rec := &responseRecorder{header: make(http.Header)}
+	c.Writer = rec
+	c.Next()
+	c.Writer = w
+	resp := rec.response()
+	resp.write(c)
+
+	if !resp.cacheable() {
+		return
+	}
+	data, err := json.Marshal(resp)
+	if err == nil {
+		err = rc.group.Set(key, data, 0)
+	}
+	if err != nil {
+		log.Printf("gee: response cache %s: %v", c.Req.RequestURI, err)
+	}
+}
+
+// private 判断请求是否带有区分用户的请求头，且该请求头不参与计算缓存key
+func (rc *responseCache) private(req *http.Request) bool {
+	for _, h := range []string{"Authorization", "Cookie"} {
+		if req.Header.Get(h) == "" {
+			continue
+		}
+		i := sort.SearchStrings(rc.vary, h)
+		if i == len(rc.vary) || rc.vary[i] != h {
+			return true
+		}
+	}
+	return false
+}
+
+// key 的第一行为方法和规范化的URL：host小写，查询参数按名称排序，之后每行一个VaryHeaders中的请求头
+// GET example.com/users?page=1&size=10
+// Accept-Language: zh-CN
+func (rc *responseCache) key(c *Context) string {
+	req := c.Req
+	var b strings.Builder
+	b.WriteString(req.Method + " " + strings.ToLower(stripHostPort(c.Host())) + req.URL.EscapedPath())
+	if query := req.URL.Query().Encode(); query != "" {
+		b.WriteString("?" + query)
+	}
+	for _, h := range rc.vary {
+		if v := req.Header.Get(h); v != "" {
+			b.WriteString("\n" + h + ": " + v)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,82 @@
+package gee
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCache(t *testing.T) {
+	calls := make(map[string]int)
+	r := New()
+	r.Use(Cache("gee-test-pages", 2<<10, &CacheOption{VaryHeaders: []string{"Accept-Language"}}))
+	r.GET("/hello/:name", func(c *Context) {
+		calls[c.Param("name")]++
+		c.SetHeader("X-Name", c.Param("name"))
+		c.Stringf(http.StatusOK, "hello %s %s", c.Param("name"), c.Req.Header.Get("Accept-Language"))
+	})
+	r.POST("/hello/:name", func(c *Context) {
+		calls["post"]++
+		c.Stringf(http.StatusOK, "posted")
+	})
+	r.GET("/missing", func(c *Context) {
+		calls["missing"]++
+		c.Stringf(http.StatusNotFound, "missing")
+	})
+	// 未命中时handler看到的是原始请求，Authorization等请求头仍然有效
+	r.GET("/auth", func(c *Context) {
+		calls["auth"]++
+		if c.Req.Header.Get("Authorization") == "" {
+			c.Stringf(http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		c.Stringf(http.StatusOK, "welcome")
+	})
+	// 只写body不调用WriteHeader，缓存的状态码为200
+	r.GET("/raw", func(c *Context) {
+		calls["raw"]++
+		c.Writer.Write([]byte("raw"))
+	})
+
+	testCases := []struct {
+		desc   string
+		method string
+		url    string
+		header map[string]string
+		body   string
+		code   int
+		key    string
+		calls  int
+	}{
+		{desc: "首次访问执行handler", method: "GET", url: "/hello/tom?b=2&a=1", body: "hello tom ", code: 200, key: "tom", calls: 1},
+		{desc: "查询参数顺序不同也命中", method: "GET", url: "/hello/tom?a=1&b=2", body: "hello tom ", code: 200, key: "tom", calls: 1},
+		{desc: "vary请求头不同", method: "GET", url: "/hello/tom?a=1&b=2", header: map[string]string{"Accept-Language": "zh"}, body: "hello tom zh", code: 200, key: "tom", calls: 2},
+		{desc: "no-cache跳过缓存", method: "GET", url: "/hello/tom?a=1&b=2", header: map[string]string{"Cache-Control": "no-cache"}, body: "hello tom ", code: 200, key: "tom", calls: 3},
+		{desc: "POST不缓存", method: "POST", url: "/hello/tom", body: "posted", code: 200, key: "post", calls: 1},
+		{desc: "POST不缓存2", method: "POST", url: "/hello/tom", body: "posted", code: 200, key: "post", calls: 2},
+		{desc: "非2xx不缓存", method: "GET", url: "/missing", body: "missing", code: 404, key: "missing", calls: 1},
+		{desc: "非2xx不缓存2", method: "GET", url: "/missing", body: "missing", code: 404, key: "missing", calls: 2},
+		{desc: "认证请求头传给handler", method: "GET", url: "/auth", header: map[string]string{"Authorization": "Bearer x"}, body: "welcome", code: 200, key: "auth", calls: 1},
+		{desc: "带认证的请求不缓存", method: "GET", url: "/auth", header: map[string]string{"Authorization": "Bearer x"}, body: "welcome", code: 200, key: "auth", calls: 2},
+		{desc: "不会得到其他用户的响应", method: "GET", url: "/auth", body: "unauthorized", code: 401, key: "auth", calls: 3},
+		{desc: "没有WriteHeader", method: "GET", url: "/raw", body: "raw", code: 200, key: "raw", calls: 1},
+		{desc: "没有WriteHeader也能命中", method: "GET", url: "/raw", body: "raw", code: 200, key: "raw", calls: 1},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(tC.method, tC.url, nil)
+			for k, v := range tC.header {
+				req.Header.Set(k, v)
+			}
+			r.ServeHTTP(w, req)
+			if w.Code != tC.code || w.Body.String() != tC.body || calls[tC.key] != tC.calls {
+				t.Errorf("%s %s got code=%d body=%q calls=%d, but we want code=%d body=%q calls=%d",
+					tC.method, tC.url, w.Code, w.Body.String(), calls[tC.key], tC.code, tC.body, tC.calls)
+			}
+			if tC.key == "tom" && w.Header().Get("X-Name") != "tom" {
+				t.Errorf("cached response should keep headers, got %v", w.Header())
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package gee
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// HTTPError 是携带HTTP状态码的错误，handler返回它时，响应使用对应的状态码和错误信息
+type HTTPError struct {
+	Code    int
+	Message string
+}
+
+func NewHTTPError(code int, format string, values ...interface{}) *HTTPError {
+	return &HTTPError{Code: code, Message: fmt.Sprintf(format, values...)}
+}
+
+func (e *HTTPError) Error() string {
+	return e.Message
+}
+
+func (e *HTTPError) StatusCode() int {
+	return e.Code
+}
+
+// statusCoder 由携带状态码的错误实现，不只是HTTPError，其他包的错误类型也可以实现它
+type statusCoder interface {
+	StatusCode() int
+}
+
+// StatusOf 返回err对应的状态码，没有携带状态码的错误一律视为500
+func StatusOf(err error) int {
+	var sc statusCoder
+	if errors.As(err, &sc) {
+		return sc.StatusCode()
+	}
+	return http.StatusInternalServerError
+}
+
+// errorBody 是错误响应的格式：{"error": "..."}或者<error><message>...</message></error>
+type errorBody struct {
+	XMLName xml.Name `json:"-" xml:"error"`
+	Message string   `json:"error" xml:"message"`
+}
+
+// Error 以协商的格式返回错误。500错误的详细信息只记录日志，不返回给客户端
+func (c *Context) Error(err error) {
+	code := StatusOf(err)
+	message := err.Error()
+	if code == http.StatusInternalServerError {
+		log.Printf("[%d] %s: %v", code, c.Req.RequestURI, err)
+		message = http.StatusText(code)
+	}
+	c.Negotiate(code, &errorBody{Message: message})
+}
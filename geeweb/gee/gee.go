@@ -2,6 +2,7 @@ package gee
 
 import (
 	"html/template"
+	"net"
 	"net/http"
 	"path"
 	"strings"
@@ -13,6 +14,9 @@ type RouterGroup struct {
 	prefix      string        // 表示一个RouteGroup
 	middlewares []HandlerFunc // support middleware, middleware就是一个输入为Context的处理函数，处理结果更新Context
 	parent      *RouterGroup  // support nesting
+	host        string        // 分组绑定的host模式，为空表示缺省host
+	noRoute     []HandlerFunc // 分组内找不到路由时的处理函数
+	noMethod    []HandlerFunc // 分组内路径存在、但方法不匹配时的处理函数
 	// 整个框架的所有资源(包括router）都是由Engine统一协调，为了访问router的能力，内嵌一个指向Engine的指针来获取router
 	engine *Engine // all groups share a Engine instance
 }
@@ -25,6 +29,7 @@ func (group *RouterGroup) Group(prefix string) *RouterGroup {
 		prefix:      group.prefix + prefix,
 		middlewares: group.middlewares,
 		parent:      group,
+		host:        group.host,
 		engine:      engine}
 
 	engine.groups = append(engine.groups, newGroup)
@@ -36,7 +41,7 @@ func (group *RouterGroup) Group(prefix string) *RouterGroup {
 }*/
 func (group *RouterGroup) addRoute(method string, comp string, handler HandlerFunc) {
 	pattern := group.prefix + comp
-	group.engine.router.addRoute(method, pattern, handler)
+	group.engine.router.addHostRoute(group.host, method, pattern, handler)
 }
 
 // GET defines the method to add GET request
@@ -57,12 +62,57 @@ func (group *RouterGroup) POST(pattern string, handler HandlerFunc) {
 	group.addRoute("POST", pattern, handler)
 }
 
+func (group *RouterGroup) PUT(pattern string, handler HandlerFunc) {
+	group.addRoute("PUT", pattern, handler)
+}
+
+func (group *RouterGroup) PATCH(pattern string, handler HandlerFunc) {
+	group.addRoute("PATCH", pattern, handler)
+}
+
+func (group *RouterGroup) DELETE(pattern string, handler HandlerFunc) {
+	group.addRoute("DELETE", pattern, handler)
+}
+
+// Handle 为任意方法添加路由
+func (group *RouterGroup) Handle(method string, pattern string, handler HandlerFunc) {
+	group.addRoute(method, pattern, handler)
+}
+
+// anyMethods 是Any注册路由时使用的方法列表
+var anyMethods = []string{
+	http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodHead,
+	http.MethodOptions, http.MethodDelete, http.MethodConnect, http.MethodTrace,
+}
+
+// Any 为所有常用的方法添加同一个路由，适用于代理等不关心方法的场景
+func (group *RouterGroup) Any(pattern string, handler HandlerFunc) {
+	for _, method := range anyMethods {
+		group.addRoute(method, pattern, handler)
+	}
+}
+
 // 只是将中间件添加到group的middlewares域中，真正起作用是根据group的middleware和对应router的handler，
 // 具体策略是先在ServerHTTP中将middlewares填充到context，然后以Ccontext来调用针对router注册的handler
 func (group *RouterGroup) Use(middlewares ...HandlerFunc) {
 	group.middlewares = append(group.middlewares, middlewares...)
 }
 
+// NoRoute 设置分组内找不到路由时的处理函数，缺省返回文本的404。
+// 请求路径匹配多个分组时，使用前缀最长的、设置了NoRoute的分组，例如/api分组返回json，其余返回html页面：
+// r.NoRoute(func(c *gee.Context) { c.HTML(http.StatusNotFound, "404.tmpl", nil) })
+// api.NoRoute(func(c *gee.Context) { c.JSON(http.StatusNotFound, gee.Obj{"error": "not found"}) })
+// 与路由一样，在此之前会先执行请求路径所在分组的中间件
+func (group *RouterGroup) NoRoute(handlers ...HandlerFunc) {
+	group.noRoute = handlers
+}
+
+// NoMethod 设置分组内路径存在、但没有对应方法的路由时的处理函数，缺省返回文本的405。
+// 执行之前响应已经设置了Allow头，列出该路径支持的方法，分组的选择规则与NoRoute相同
+func (group *RouterGroup) NoMethod(handlers ...HandlerFunc) {
+	group.noMethod = handlers
+}
+
 // group.Static("/assets", "/usr/geektutu/blog/static")
 // relativePath指定访问URL中静态资源的路径的根路径，root指定文件在本地磁盘所在的路径
 func (group *RouterGroup) Static(relativePath string, root string) {
@@ -108,6 +158,19 @@ type Engine struct {
 	// for模板支持
 	htmlTemplates *template.Template
 	funcMap       template.FuncMap
+
+	// 路由选项，缺省都不开启，此时/a//b、/a/b/都会被当作/a/b处理
+	// RedirectTrailingSlash 请求路径与路由只相差末尾的/时，重定向到注册的形式
+	RedirectTrailingSlash bool
+	// RedirectFixedPath 清理路径中多余的/以及.和..，找不到路由时再忽略大小写查找，能找到则重定向到修正后的路径
+	RedirectFixedPath bool
+	// UseRawPath 使用URL.RawPath（如果有）匹配路由，这样%2F这样转义的/会作为参数值的一部分，而不是路径分隔符
+	UseRawPath bool
+
+	// 可信代理的网段，通过SetTrustedProxies设置
+	trustedCIDRs []*net.IPNet
+	// 分组的API版本，在路由之前改写请求路径
+	versions []*Versions
 }
 
 // New is the constructor of gee.Engine
@@ -123,20 +186,86 @@ func (engine *Engine) Run(addr string) (err error) {
 	return http.ListenAndServe(addr, engine)
 }
 
+// Host 返回绑定到指定host的RouterGroup，用于在同一个进程中为不同的（子）域名提供不同的路由，例如：
+// api := engine.Host(":sub.example.com")
+// 其中:sub可以匹配任意一级子域名，并作为参数捕获，通过c.Param("sub")获取。
+// 请求的host没有匹配的分组，或者host分组下没有匹配的路由时，回退到缺省host的路由。
+func (engine *Engine) Host(pattern string) *RouterGroup {
+	// 提前创建路由树，这样即使分组下只有NoRoute，也能按host匹配
+	engine.router.hostRoot(pattern)
+	newGroup := &RouterGroup{
+		parent: engine.RouterGroup,
+		host:   pattern,
+		engine: engine}
+
+	engine.groups = append(engine.groups, newGroup)
+	return newGroup
+}
+
 func (engine *Engine) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	c := newContext(w, req)
+	c.engine = engine
+	defer c.finish()
+	for _, vs := range engine.versions {
+		if vs.rewrite(c) {
+			break
+		}
+	}
+	engine.router.handle(c)
+}
 
+// middlewares 收集作用于请求的中间件，host为命中的路由树对应的host模式。
+// Engine自身的中间件作用于所有请求，其他分组的host必须与之一致，且请求路径以分组的前缀开头
+func (engine *Engine) middlewares(host string, path string) []HandlerFunc {
 	var middlewares []HandlerFunc
 	for _, group := range engine.groups {
-		if strings.HasPrefix(c.Path, group.prefix) {
+		if group != engine.RouterGroup && group.host != host {
+			continue
+		}
+		if strings.HasPrefix(path, group.prefix) {
 			middlewares = append(middlewares, group.middlewares...)
 		}
 	}
+	return middlewares
+}
 
-	c.engine = engine
+// noRouteHandlers 返回处理404的函数，多个分组匹配时前缀最长的优先
+func (engine *Engine) noRouteHandlers(host string, path string) []HandlerFunc {
+	if handlers := engine.deepestGroup(host, path, func(g *RouterGroup) []HandlerFunc { return g.noRoute }); handlers != nil {
+		return handlers
+	}
+	return []HandlerFunc{func(c *Context) {
+		c.Stringf(http.StatusNotFound, "404 NOT FOUND: %s\n", c.Path)
+	}}
+}
 
-	c.handlers = middlewares
-	engine.router.handle(c)
+// noMethodHandlers 返回处理405的函数
+func (engine *Engine) noMethodHandlers(host string, path string) []HandlerFunc {
+	if handlers := engine.deepestGroup(host, path, func(g *RouterGroup) []HandlerFunc { return g.noMethod }); handlers != nil {
+		return handlers
+	}
+	return []HandlerFunc{func(c *Context) {
+		c.Stringf(http.StatusMethodNotAllowed, "405 METHOD NOT ALLOWED: %s\n", c.Path)
+	}}
+}
+
+// deepestGroup 在匹配host和路径前缀的分组中，找到前缀最长、且get不为空的分组
+func (engine *Engine) deepestGroup(host string, path string, get func(*RouterGroup) []HandlerFunc) []HandlerFunc {
+	var handlers []HandlerFunc
+	depth := -1
+	for _, group := range engine.groups {
+		if group != engine.RouterGroup && group.host != host {
+			continue
+		}
+		if !strings.HasPrefix(path, group.prefix) || len(get(group)) == 0 {
+			continue
+		}
+		// 前缀相同时，后创建的分组（例如host分组）优先于Engine
+		if len(group.prefix) >= depth {
+			handlers, depth = get(group), len(group.prefix)
+		}
+	}
+	return handlers
 }
 
 func (engine *Engine) SetFuncMap(fm template.FuncMap) {
@@ -9,6 +9,6 @@ func Logger() HandlerFunc {
 	return func(c *Context) {
 		t := time.Now()
 		c.Next()
-		log.Printf("[%d] %s in %v", c.StatusCode, c.Req.RequestURI, time.Since(t))
+		log.Printf("[%d] %s %s in %v", c.StatusCode, c.ClientIP(), c.Req.RequestURI, time.Since(t))
 	}
 }
@@ -0,0 +1,297 @@
+package gee
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"geektutu/geerpc/xclient"
+)
+
+// ProxyOption 是反向代理的配置
+type ProxyOption struct {
+	// StripPrefix 转发前从请求路径中去掉的前缀，使用group.Proxy时自动设置为分组前缀+relativePath
+	StripPrefix string
+	// Mode 在多个upstream之间负载均衡的模式，与xclient相同，支持随机和轮询
+	Mode xclient.SelectMode
+	// HealthCheckPath 健康检查访问的路径，为空表示不做健康检查
+	HealthCheckPath string
+	// HealthCheckInterval 健康检查的间隔，小于等于0时使用缺省值
+	HealthCheckInterval time.Duration
+	// Context 结束时停止健康检查，为nil时健康检查一直运行到进程退出
+	Context context.Context
+	// Retries 幂等请求因为连接失败而转发失败时，换一个upstream重试的次数
+	Retries int
+	// Transport 用于转发请求，为nil时使用http.DefaultTransport
+	Transport http.RoundTripper
+}
+
+var DefaultProxyOption = ProxyOption{
+	Mode:                xclient.RoundRobinMode,
+	HealthCheckInterval: 10 * time.Second,
+	Retries:             2,
+}
+
+func parseProxyOption(opt *ProxyOption) *ProxyOption {
+	o := DefaultProxyOption
+	if opt != nil {
+		o = *opt
+	}
+	if o.HealthCheckInterval <= 0 {
+		o.HealthCheckInterval = DefaultProxyOption.HealthCheckInterval
+	}
+	if o.Transport == nil {
+		o.Transport = http.DefaultTransport
+	}
+	if o.Context == nil {
+		o.Context = context.Background()
+	}
+	return &o
+}
+
+// 这些头只对单个连接有效，代理不能转发，参考RFC 7230 6.1
+var hopHeaders = []string{
+	"Connection",
+	"Proxy-Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+func removeHopHeaders(h http.Header) {
+	for _, f := range h["Connection"] {
+		for _, sf := range strings.Split(f, ",") {
+			if sf = strings.TrimSpace(sf); sf != "" {
+				h.Del(sf)
+			}
+		}
+	}
+	for _, f := range hopHeaders {
+		h.Del(f)
+	}
+}
+
+// isDialError 判断是否是建立连接时的错误，此时upstream一定还没有收到请求
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// 幂等的请求才能在连接失败时安全的重试
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// proxy 在多个upstream之间转发请求
+// 健康的upstream保存在xclient.MultiServersDiscovery中，这样就可以直接复用xclient的负载均衡
+type proxy struct {
+	opt       *ProxyOption
+	upstreams []string
+	targets   map[string]*url.URL
+	d         *xclient.MultiServersDiscovery
+
+	mu      sync.Mutex
+	healthy map[string]bool
+}
+
+// Proxy 返回一个将请求转发到upstreams的HandlerFunc，upstream的格式为http://host:port/path
+// 转发的路径为upstream的路径加上去掉opt.StripPrefix之后的请求路径
+func Proxy(upstreams []string, opt *ProxyOption) HandlerFunc {
+	if len(upstreams) == 0 {
+		panic("gee: proxy needs at least one upstream")
+	}
+	p := &proxy{
+		opt:       parseProxyOption(opt),
+		upstreams: upstreams,
+		targets:   make(map[string]*url.URL, len(upstreams)),
+		healthy:   make(map[string]bool, len(upstreams)),
+	}
+	for _, upstream := range upstreams {
+		target, err := url.Parse(upstream)
+		if err != nil {
+			panic(fmt.Sprintf("gee: invalid upstream %s: %v", upstream, err))
+		}
+		p.targets[upstream] = target
+		p.healthy[upstream] = true
+	}
+	p.d = xclient.NewMultiServersDiscovery(p.healthyUpstreams())
+	if p.opt.HealthCheckPath != "" {
+		go p.healthCheck()
+	}
+	return p.serve
+}
+
+// Proxy 将relativePath及其下所有路径、所有方法的请求都转发到upstreams，转发时去掉分组前缀和relativePath
+// group.Proxy("/users", []string{"http://10.0.0.1:8080", "http://10.0.0.2:8080"}, nil)
+func (group *RouterGroup) Proxy(relativePath string, upstreams []string, opt *ProxyOption) {
+	o := parseProxyOption(opt)
+	o.StripPrefix = path.Join(group.prefix, relativePath)
+	handler := Proxy(upstreams, o)
+	group.Any(relativePath, handler)
+	group.Any(path.Join(relativePath, "/*proxypath"), handler)
+}
+
+// 按照upstreams的顺序返回健康的upstream
+func (p *proxy) healthyUpstreams() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	servers := make([]string, 0, len(p.upstreams))
+	for _, upstream := range p.upstreams {
+		if p.healthy[upstream] {
+			servers = append(servers, upstream)
+		}
+	}
+	return servers
+}
+
+func (p *proxy) setHealthy(upstream string, healthy bool) {
+	p.mu.Lock()
+	changed := p.healthy[upstream] != healthy
+	p.healthy[upstream] = healthy
+	p.mu.Unlock()
+	if changed {
+		log.Printf("proxy: upstream %s healthy=%v", upstream, healthy)
+		_ = p.d.Update(p.healthyUpstreams())
+	}
+}
+
+// healthCheck 定期访问每个upstream的HealthCheckPath，返回5xx或者连接失败的upstream不再参与负载均衡
+func (p *proxy) healthCheck() {
+	client := &http.Client{Transport: p.opt.Transport, Timeout: p.opt.HealthCheckInterval}
+	ticker := time.NewTicker(p.opt.HealthCheckInterval)
+	defer ticker.Stop()
+	done := p.opt.Context.Done()
+	for {
+		for _, upstream := range p.upstreams {
+			target := *p.targets[upstream]
+			target.Path = singleJoiningSlash(target.Path, p.opt.HealthCheckPath)
+			resp, err := client.Get(target.String())
+			if err == nil {
+				_ = resp.Body.Close()
+			}
+			p.setHealthy(upstream, err == nil && resp.StatusCode < http.StatusInternalServerError)
+		}
+		select {
+		case <-ticker.C:
+		case <-done:
+			return
+		}
+	}
+}
+
+func singleJoiningSlash(a, b string) string {
+	aslash := strings.HasSuffix(a, "/")
+	bslash := strings.HasPrefix(b, "/")
+	switch {
+	case aslash && bslash:
+		return a + b[1:]
+	case !aslash && !bslash:
+		return a + "/" + b
+	}
+	return a + b
+}
+
+// 构造转发给target的请求
+func (p *proxy) outRequest(c *Context, target *url.URL, body []byte) *http.Request {
+	out := c.Req.Clone(c.Req.Context())
+	out.RequestURI = ""
+	out.URL.Scheme = target.Scheme
+	out.URL.Host = target.Host
+	out.URL.Path = singleJoiningSlash(target.Path, strings.TrimPrefix(c.Req.URL.Path, p.opt.StripPrefix))
+	out.URL.RawPath = ""
+	if body != nil {
+		out.Body = ioutil.NopCloser(bytes.NewReader(body))
+		out.ContentLength = int64(len(body))
+	}
+	removeHopHeaders(out.Header)
+
+	// 追加X-Forwarded-*，让upstream知道客户端的真实信息。
+	// 对端不是可信代理时，它带来的转发头是不可信的，需要丢弃
+	if ip, _, err := net.SplitHostPort(c.Req.RemoteAddr); err == nil {
+		prior := out.Header.Get("X-Forwarded-For")
+		if prior != "" && c.engine.isTrustedProxy(ip) {
+			ip = prior + ", " + ip
+		}
+		out.Header.Set("X-Forwarded-For", ip)
+	}
+	out.Header.Del("Forwarded")
+	out.Header.Del("X-Real-IP")
+	out.Header.Set("X-Forwarded-Host", c.Host())
+	out.Header.Set("X-Forwarded-Proto", c.Scheme())
+	return out
+}
+
+func (p *proxy) serve(c *Context) {
+	attempts := 1
+	var body []byte
+	if isIdempotent(c.Method) {
+		attempts += p.opt.Retries
+		// 重试时需要再次发送body，因此先把body读到内存中
+		if c.Req.Body != nil && c.Req.Body != http.NoBody {
+			var err error
+			if body, err = ioutil.ReadAll(c.Req.Body); err != nil {
+				c.Stringf(http.StatusBadRequest, "read request body: %v", err)
+				return
+			}
+		}
+	}
+
+	var err error
+	for i := 0; i < attempts; i++ {
+		upstream, e := p.d.Get(p.opt.Mode)
+		if e != nil {
+			err = e
+			break
+		}
+		var resp *http.Response
+		resp, err = p.opt.Transport.RoundTrip(p.outRequest(c, p.targets[upstream], body))
+		if err != nil {
+			log.Printf("proxy: forward %s %s to %s failed: %v", c.Method, c.Path, upstream, err)
+			if p.opt.HealthCheckPath != "" {
+				p.setHealthy(upstream, false)
+			}
+			// 只有连接失败才重试，此时upstream还没有处理请求；
+			// 超时或者连接被重置时upstream可能已经处理了请求，不能再发送一次
+			if !isDialError(err) {
+				break
+			}
+			continue
+		}
+		p.copyResponse(c, resp)
+		return
+	}
+	c.Stringf(http.StatusBadGateway, "502 BAD GATEWAY: %v\n", err)
+}
+
+func (p *proxy) copyResponse(c *Context, resp *http.Response) {
+	defer resp.Body.Close()
+	removeHopHeaders(resp.Header)
+	for k, vv := range resp.Header {
+		for _, v := range vv {
+			c.Writer.Header().Add(k, v)
+		}
+	}
+	c.Status(resp.StatusCode)
+	if _, err := io.Copy(c.Writer, resp.Body); err != nil {
+		log.Printf("proxy: copy response body: %v", err)
+	}
+}
@@ -0,0 +1,139 @@
+package gee
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"geektutu/geerpc/xclient"
+)
+
+func newUpstream(name string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("X-Upstream", name)
+		fmt.Fprintf(w, "%s %s %s", name, req.URL.Path, req.Header.Get("X-Forwarded-For"))
+	}))
+}
+
+func TestProxy(t *testing.T) {
+	a, b := newUpstream("a"), newUpstream("b")
+	defer a.Close()
+	defer b.Close()
+
+	r := New()
+	api := r.Group("/api")
+	api.Proxy("/svc", []string{a.URL, b.URL + "/base"}, &ProxyOption{Mode: xclient.RoundRobinMode})
+
+	got := make(map[string]bool)
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/api/svc/users?id=1", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("proxy got code %d, but we want 200", w.Code)
+		}
+		got[w.Body.String()] = true
+	}
+	want := map[string]bool{"a /users 10.0.0.1": true, "b /base/users 10.0.0.1": true}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("round robin proxy got %v, but we want %v", got, want)
+	}
+}
+
+func TestProxyRetry(t *testing.T) {
+	a := newUpstream("a")
+	defer a.Close()
+	dead := newUpstream("dead")
+	dead.Close()
+
+	handler := Proxy([]string{dead.URL, a.URL}, &ProxyOption{Mode: xclient.RoundRobinMode, Retries: 1})
+	r := New()
+	r.Any("/*path", handler)
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/ping", nil))
+		if w.Code != http.StatusOK || w.Header().Get("X-Upstream") != "a" {
+			t.Errorf("GET should be retried on upstream a, but got code=%d body=%q", w.Code, w.Body.String())
+		}
+	}
+
+	handler = Proxy([]string{dead.URL}, &ProxyOption{Retries: 1})
+	r = New()
+	r.Any("/*path", handler)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/ping", nil))
+	if w.Code != http.StatusBadGateway {
+		t.Errorf("POST to dead upstream got code=%d, but we want 502", w.Code)
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestProxyRetryDialOnly(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		err   error
+		tries int
+	}{
+		{desc: "连接失败时重试", err: &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, tries: 3},
+		{desc: "upstream可能已经处理了请求，不重试", err: &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset")}, tries: 1},
+		{desc: "超时不重试", err: errors.New("net/http: timeout awaiting response headers"), tries: 1},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			tries := 0
+			transport := roundTripperFunc(func(*http.Request) (*http.Response, error) {
+				tries++
+				return nil, tC.err
+			})
+			r := New()
+			r.Any("/*path", Proxy([]string{"http://a", "http://b"}, &ProxyOption{Retries: 2, Transport: transport}))
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest("GET", "/ping", nil))
+			if w.Code != http.StatusBadGateway || tries != tC.tries {
+				t.Errorf("got code=%d tries=%d, but we want code=502 tries=%d", w.Code, tries, tC.tries)
+			}
+		})
+	}
+}
+
+func TestProxyHealthCheckStop(t *testing.T) {
+	var checks int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&checks, 1)
+	}))
+	defer upstream.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	Proxy([]string{upstream.URL}, &ProxyOption{HealthCheckPath: "/health", HealthCheckInterval: 5 * time.Millisecond, Context: ctx})
+	for deadline := time.Now().Add(time.Second); atomic.LoadInt32(&checks) < 2; {
+		if time.Now().After(deadline) {
+			t.Fatalf("health check not running")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	// 等待正在进行的检查结束
+	time.Sleep(20 * time.Millisecond)
+	n := atomic.LoadInt32(&checks)
+	time.Sleep(30 * time.Millisecond)
+	if atomic.LoadInt32(&checks) != n {
+		t.Errorf("health check should stop after context is done")
+	}
+
+	// 负的间隔使用缺省值，而不是让time.NewTicker panic
+	if opt := parseProxyOption(&ProxyOption{HealthCheckInterval: -time.Second}); opt.HealthCheckInterval != DefaultProxyOption.HealthCheckInterval {
+		t.Errorf("expect default interval, got %v", opt.HealthCheckInterval)
+	}
+}
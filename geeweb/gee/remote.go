@@ -0,0 +1,194 @@
+package gee
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// SetTrustedProxies 设置可信代理的IP或CIDR，例如[]string{"10.0.0.0/8", "127.0.0.1"}。
+// 只有直接连接的对端在其中时，才会解析X-Forwarded-For、X-Real-IP和Forwarded等请求头，
+// 缺省不信任任何代理，此时ClientIP、Scheme、Host都只取决于连接本身
+func (engine *Engine) SetTrustedProxies(proxies []string) error {
+	var cidrs []*net.IPNet
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return fmt.Errorf("gee: invalid trusted proxy %q", proxy)
+			}
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			cidrs = append(cidrs, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, cidr, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return fmt.Errorf("gee: invalid trusted proxy %q: %v", proxy, err)
+		}
+		cidrs = append(cidrs, cidr)
+	}
+	engine.trustedCIDRs = cidrs
+	return nil
+}
+
+func (engine *Engine) isTrustedProxy(ip string) bool {
+	addr := net.ParseIP(ip)
+	if engine == nil || addr == nil {
+		return false
+	}
+	for _, cidr := range engine.trustedCIDRs {
+		if cidr.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// forwardedHop 是请求经过的一跳：ip是这一跳的地址，proto和host是下一跳（代理）收到请求时看到的协议和host
+type forwardedHop struct {
+	ip    string
+	proto string
+	host  string
+}
+
+// ClientIP 返回客户端的IP。从直接连接的对端开始，沿着转发链从右往左，
+// 跳过可信代理，第一个不可信的地址就是客户端，转发链全部可信时取最左边的地址
+func (c *Context) ClientIP() string {
+	return c.remote().ip
+}
+
+// Scheme 返回客户端请求使用的协议，http或https
+func (c *Context) Scheme() string {
+	return c.remote().proto
+}
+
+// Host 返回客户端请求的host，可能带有端口
+func (c *Context) Host() string {
+	return c.remote().host
+}
+
+// remote 解析客户端的信息，结果缓存在Context中
+func (c *Context) remote() *forwardedHop {
+	if c.client != nil {
+		return c.client
+	}
+	direct := &forwardedHop{ip: c.Req.RemoteAddr, proto: "http", host: c.Req.Host}
+	if ip, _, err := net.SplitHostPort(c.Req.RemoteAddr); err == nil {
+		direct.ip = ip
+	}
+	if c.Req.TLS != nil {
+		direct.proto = "https"
+	}
+	c.client = direct
+	if !c.engine.isTrustedProxy(direct.ip) {
+		return c.client
+	}
+
+	hops := c.forwardedHops()
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := hops[i]
+		if hop.proto == "" {
+			hop.proto = c.client.proto
+		}
+		if hop.host == "" {
+			hop.host = c.client.host
+		}
+		// 地址无法解析（比如Forwarded中的unknown或者混淆的标识）时，停在最后一个可信代理上
+		if net.ParseIP(hop.ip) == nil {
+			break
+		}
+		c.client = &hop
+		if !c.engine.isTrustedProxy(hop.ip) {
+			break
+		}
+	}
+	return c.client
+}
+
+// forwardedHops 解析转发链，顺序为从客户端到最后一个代理。
+// 优先使用标准的Forwarded头，其次是X-Forwarded-For，搭配X-Forwarded-Proto和X-Forwarded-Host，最后是X-Real-IP
+func (c *Context) forwardedHops() []forwardedHop {
+	header := c.Req.Header
+	if values := header.Values("Forwarded"); len(values) > 0 {
+		return parseForwarded(strings.Join(values, ","))
+	}
+	if values := header.Values("X-Forwarded-For"); len(values) > 0 {
+		ips := splitHeaderList(strings.Join(values, ","))
+		protos := splitHeaderList(header.Get("X-Forwarded-Proto"))
+		hosts := splitHeaderList(header.Get("X-Forwarded-Host"))
+		hops := make([]forwardedHop, len(ips))
+		for i := range ips {
+			hops[i].ip = ips[i]
+			hops[i].proto = alignRight(protos, len(ips), i)
+			hops[i].host = alignRight(hosts, len(ips), i)
+		}
+		return hops
+	}
+	if ip := strings.TrimSpace(header.Get("X-Real-IP")); ip != "" {
+		return []forwardedHop{{
+			ip:    ip,
+			proto: strings.TrimSpace(header.Get("X-Forwarded-Proto")),
+			host:  strings.TrimSpace(header.Get("X-Forwarded-Host")),
+		}}
+	}
+	return nil
+}
+
+func splitHeaderList(value string) []string {
+	var list []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// alignRight 每个代理都会在列表末尾追加自己的值，因此从右边对齐取第i个值；
+// 只有一个值时，认为它是最外层的代理设置的，作用于整个转发链
+func alignRight(values []string, n int, i int) string {
+	if len(values) == 1 {
+		return values[0]
+	}
+	if j := i - (n - len(values)); j >= 0 && j < len(values) {
+		return values[j]
+	}
+	return ""
+}
+
+// parseForwarded 解析RFC 7239定义的Forwarded头，例如
+// Forwarded: for=192.0.2.60;proto=https;host=example.com, for="[2001:db8::1]:4711"
+func parseForwarded(value string) []forwardedHop {
+	var hops []forwardedHop
+	for _, element := range strings.Split(value, ",") {
+		var hop forwardedHop
+		for _, pair := range strings.Split(element, ";") {
+			kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			v := strings.Trim(kv[1], `"`)
+			switch strings.ToLower(kv[0]) {
+			case "for":
+				hop.ip = forwardedNode(v)
+			case "proto":
+				hop.proto = strings.ToLower(v)
+			case "host":
+				hop.host = v
+			}
+		}
+		hops = append(hops, hop)
+	}
+	return hops
+}
+
+// forwardedNode 去掉节点标识中的端口和IPv6的方括号
+func forwardedNode(node string) string {
+	if host, _, err := net.SplitHostPort(node); err == nil {
+		return host
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(node, "["), "]")
+}
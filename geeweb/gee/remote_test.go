@@ -0,0 +1,68 @@
+package gee
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	r := New()
+	if err := r.SetTrustedProxies([]string{"10.0.0.0/8", "192.168.1.1", "::1"}); err != nil {
+		t.Fatal(err)
+	}
+	r.GET("/", func(c *Context) {
+		c.Stringf(http.StatusOK, "%s %s %s", c.ClientIP(), c.Scheme(), c.Host())
+	})
+
+	testCases := []struct {
+		desc    string
+		remote  string
+		tls     bool
+		headers map[string]string
+		want    string
+	}{
+		{"untrusted peer ignores headers", "1.2.3.4:1000", false,
+			map[string]string{"X-Forwarded-For": "5.6.7.8", "X-Forwarded-Proto": "https", "X-Forwarded-Host": "evil.com"},
+			"1.2.3.4 http example.com"},
+		{"direct tls", "1.2.3.4:1000", true, nil, "1.2.3.4 https example.com"},
+		{"trusted peer without headers", "10.0.0.1:1000", false, nil, "10.0.0.1 http example.com"},
+		{"skip trusted hops", "10.0.0.1:1000", false,
+			map[string]string{"X-Forwarded-For": "9.9.9.9, 5.6.7.8, 192.168.1.1", "X-Forwarded-Proto": "https", "X-Forwarded-Host": "geektutu.com"},
+			"5.6.7.8 https geektutu.com"},
+		{"all hops trusted", "10.0.0.1:1000", false,
+			map[string]string{"X-Forwarded-For": "10.1.1.1, 192.168.1.1"}, "10.1.1.1 http example.com"},
+		{"x-real-ip", "[::1]:1000", false, map[string]string{"X-Real-IP": "5.6.7.8"}, "5.6.7.8 http example.com"},
+		{"forwarded", "10.0.0.1:1000", false,
+			map[string]string{"Forwarded": `for=5.6.7.8;proto=https;host=geektutu.com, for="[2001:db8::1]:4711";proto=http`},
+			"2001:db8::1 http example.com"},
+		{"forwarded through trusted hop", "10.0.0.1:1000", false,
+			map[string]string{"Forwarded": `for=5.6.7.8;proto=https;host=geektutu.com, for=10.2.2.2`},
+			"5.6.7.8 https geektutu.com"},
+		{"forwarded unknown", "10.0.0.1:1000", false,
+			map[string]string{"Forwarded": `for=unknown, for=10.2.2.2`}, "10.2.2.2 http example.com"},
+	}
+	for _, tc := range testCases {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = tc.remote
+		if tc.tls {
+			req.TLS = &tls.ConnectionState{}
+		}
+		for k, v := range tc.headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Body.String() != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.desc, w.Body.String(), tc.want)
+		}
+	}
+
+	if err := r.SetTrustedProxies([]string{"10.0.0.0/33"}); err == nil {
+		t.Error("invalid cidr should fail")
+	}
+	if err := r.SetTrustedProxies([]string{"localhost"}); err == nil {
+		t.Error("invalid ip should fail")
+	}
+}
@@ -1,19 +1,34 @@
 package gee
 
 import (
-	"fmt"
+	"log"
+	"net"
 	"net/http"
+	"net/url"
+	"path"
+	"sort"
 	"strings"
 )
 
 // router
 // roots key eg, roots['GET'] roots['POST']
 // handlers key eg, handlers['GET-/p/:lang/doc'], handlers['POST-/p/book']
+// 绑定了host的路由，handlers的key前面再加上host，例如handlers[':sub.example.com-GET-/p/book']
 type router struct {
 	// root for each method(get, post, ...)
 	roots map[string]*node
 	//
 	handlers map[string]HandlerFunc
+	// 按host划分的路由树，按注册顺序匹配；没有匹配的host（或者host下没有匹配的路由）时，使用缺省的roots
+	hosts []*hostRoot
+}
+
+// hostRoot 是某个host模式下的路由树，例如 :sub.example.com
+// host按.分割为多个label，其中以:开头的label可以匹配任意值，并作为参数捕获
+type hostRoot struct {
+	pattern string
+	labels  []string
+	roots   map[string]*node
 }
 
 func newRouter() *router {
@@ -23,25 +38,107 @@ func newRouter() *router {
 	}
 }
 
+func handlerKey(host, method, pattern string) string {
+	if host == "" {
+		return method + "-" + pattern
+	}
+	return host + "-" + method + "-" + pattern
+}
+
 func (r *router) addRoute(method string, pattern string, handler HandlerFunc) {
-	fmt.Println("add route:", method, pattern)
+	r.addHostRoute("", method, pattern, handler)
+}
+
+// addHostRoute 在host对应的路由树中添加路由，host为空表示缺省的路由树
+func (r *router) addHostRoute(host string, method string, pattern string, handler HandlerFunc) {
+	log.Printf("Route %4s - %s%s", method, host, pattern)
 	parts := parsePattern(pattern)
 
-	key := method + "-" + pattern
-	_, ok := r.roots[method]
+	roots := r.roots
+	if host != "" {
+		roots = r.hostRoot(host).roots
+	}
+	_, ok := roots[method]
 	if !ok {
-		r.roots[method] = &node{}
+		roots[method] = &node{}
+	}
+
+	roots[method].insert(pattern, parts, 0)
+	r.handlers[handlerKey(host, method, pattern)] = handler
+}
+
+// 找到host模式对应的路由树，不存在则创建
+func (r *router) hostRoot(pattern string) *hostRoot {
+	for _, hr := range r.hosts {
+		if hr.pattern == pattern {
+			return hr
+		}
+	}
+	hr := &hostRoot{
+		pattern: pattern,
+		labels:  strings.Split(strings.ToLower(pattern), "."),
+		roots:   map[string]*node{},
+	}
+	r.hosts = append(r.hosts, hr)
+	return hr
+}
+
+// match 判断请求的host是否匹配该模式，匹配时返回捕获的参数
+func (hr *hostRoot) match(host string) (map[string]string, bool) {
+	labels := strings.Split(strings.ToLower(host), ".")
+	if len(labels) != len(hr.labels) {
+		return nil, false
+	}
+	params := make(map[string]string)
+	for i, label := range hr.labels {
+		if strings.HasPrefix(label, ":") {
+			params[label[1:]] = labels[i]
+			continue
+		}
+		if label != labels[i] {
+			return nil, false
+		}
 	}
+	return params, true
+}
 
-	r.roots[method].insert(pattern, parts, 0)
-	r.handlers[key] = handler
+// 请求中的host可能带有端口，例如 api.example.com:9999，匹配时去掉端口
+func stripHostPort(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
 }
 
 func (r *router) getRoute(method string, path string) (*node, map[string]string) {
+	return searchRoots(r.roots, method, path)
+}
+
+// getHostRoute 先在匹配host的路由树中查找，找不到再回退到缺省的路由树
+// 返回命中的host模式（缺省路由树为空串）、路由节点以及参数（包括host中捕获的参数）
+func (r *router) getHostRoute(method string, host string, path string) (string, *node, map[string]string) {
+	host = stripHostPort(host)
+	for _, hr := range r.hosts {
+		hostParams, ok := hr.match(host)
+		if !ok {
+			continue
+		}
+		if n, params := searchRoots(hr.roots, method, path); n != nil {
+			for k, v := range hostParams {
+				params[k] = v
+			}
+			return hr.pattern, n, params
+		}
+	}
+	n, params := r.getRoute(method, path)
+	return "", n, params
+}
+
+func searchRoots(roots map[string]*node, method string, path string) (*node, map[string]string) {
 	searchParts := parsePattern(path)
 	params := make(map[string]string)
 
-	root, ok := r.roots[method]
+	root, ok := roots[method]
 	if !ok {
 		return nil, nil
 	}
@@ -52,7 +149,7 @@ func (r *router) getRoute(method string, path string) (*node, map[string]string)
 		parts := parsePattern(n.pattern)
 		for index, part := range parts {
 			if part[0] == ':' {
-				params[part[1:]] = searchParts[index]
+				params[paramName(part)] = searchParts[index]
 			}
 			if part[0] == '*' && len(part) > 1 {
 				params[part[1:]] = strings.Join(searchParts[index:], "/")
@@ -64,25 +161,201 @@ func (r *router) getRoute(method string, path string) (*node, map[string]string)
 	return nil, nil
 }
 
+// getFixedPath 清理路径后，忽略大小写再查找一次路由，找到时返回按注册形式修正后的路径
+func (r *router) getFixedPath(method string, host string, path string) (string, bool) {
+	searchParts := parsePattern(cleanPath(path))
+	host = stripHostPort(host)
+	var roots []map[string]*node
+	for _, hr := range r.hosts {
+		if _, ok := hr.match(host); ok {
+			roots = append(roots, hr.roots)
+		}
+	}
+	roots = append(roots, r.roots)
+	for _, root := range roots {
+		if root[method] == nil {
+			continue
+		}
+		if n := root[method].searchFold(searchParts, 0); n != nil {
+			return fixPath(n.pattern, searchParts), true
+		}
+	}
+	return "", false
+}
+
+// fixPath 按照路由的注册形式重建请求路径：静态部分使用注册时的写法，参数部分保持请求中的原值
+func fixPath(pattern string, searchParts []string) string {
+	var fixed []string
+	for i, part := range parsePattern(pattern) {
+		if part[0] == '*' {
+			fixed = append(fixed, searchParts[i:]...)
+			break
+		}
+		if part[0] == ':' {
+			fixed = append(fixed, searchParts[i])
+			continue
+		}
+		fixed = append(fixed, part)
+	}
+	p := "/" + strings.Join(fixed, "/")
+	if p != "/" && strings.HasSuffix(pattern, "/") {
+		p += "/"
+	}
+	return p
+}
+
+// cleanPath 去掉多余的/，解析.和..，但保留末尾的/
+func cleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	if p[0] != '/' {
+		p = "/" + p
+	}
+	cleaned := path.Clean(p)
+	if cleaned != "/" && strings.HasSuffix(p, "/") {
+		cleaned += "/"
+	}
+	return cleaned
+}
+
+// canonicalPath 根据Engine的路由选项，计算请求路径对应于路由pattern的规范形式
+// 返回值与请求路径不同时，需要重定向到该规范形式
+func canonicalPath(engine *Engine, p string, pattern string) string {
+	if engine.RedirectFixedPath {
+		p = cleanPath(p)
+	}
+	// parsePattern会忽略空的片段，//evil.com/同样能匹配/:name。
+	// 以//或/\开头的Location会被浏览器当作协议相对的URL，重定向到其他host，因此总是去掉开头多余的/和\
+	if len(p) > 1 && (p[1] == '/' || p[1] == '\\') {
+		p = "/" + strings.TrimLeft(p, "/\\")
+	}
+	// 以*结尾的路由匹配任意后缀，末尾有没有/都是合法的
+	if engine.RedirectTrailingSlash && p != "/" && !strings.Contains(pattern, "*") {
+		if want := strings.HasSuffix(pattern, "/"); strings.HasSuffix(p, "/") != want {
+			if want {
+				p += "/"
+			} else {
+				p = strings.TrimSuffix(p, "/")
+			}
+		}
+	}
+	return p
+}
+
+// redirect 重定向到location，并保留请求中的查询参数
+// GET和HEAD请求使用301，其他方法使用308，保证客户端重定向后不会改变请求方法和body
+func redirect(location string) HandlerFunc {
+	return func(c *Context) {
+		code := http.StatusMovedPermanently
+		if c.Method != http.MethodGet && c.Method != http.MethodHead {
+			code = http.StatusPermanentRedirect
+		}
+		if c.Req.URL.RawQuery != "" {
+			location += "?" + c.Req.URL.RawQuery
+		}
+		c.Redirect(code, location)
+	}
+}
+
 func (r *router) handle(c *Context) {
 	defer Recover()
+	engine := c.engine
+	// 使用RawPath匹配时，%2F这样转义的/不会被当作路径分隔符，参数的值在匹配之后再反转义
+	searchPath := c.Path
+	useRawPath := engine.UseRawPath && c.Req.URL.RawPath != ""
+	if useRawPath {
+		searchPath = c.Req.URL.RawPath
+	}
 	// handlers的key是pattern，不是path，我们需要根据path解析得到pattern
 	//key := c.Method + "-" + c.Path
-	n, params := r.getRoute(c.Method, c.Path)
+	host, n, params := r.getHostRoute(c.Method, c.Host(), searchPath)
 	//fmt.Println("get router:", n.pattern, params)
+	// 只有确定了命中哪棵路由树，才能确定哪些分组的中间件会作用于该请求
+	c.handlers = engine.middlewares(host, c.Path)
 	if n != nil {
-		key := c.Method + "-" + n.pattern
+		if location := canonicalPath(engine, searchPath, n.pattern); location != searchPath {
+			c.handlers = append(c.handlers, redirect(location))
+			c.Next()
+			return
+		}
+		if useRawPath {
+			for k, v := range params {
+				if unescaped, err := url.PathUnescape(v); err == nil {
+					params[k] = unescaped
+				}
+			}
+		}
+		key := handlerKey(host, c.Method, n.pattern)
 		c.Params = params
 		c.handlers = append(c.handlers, r.handlers[key])
 		//r.handlers[key](c)
 	} else {
-		c.handlers = append(c.handlers, func(c *Context) {
-			c.Stringf(http.StatusNotFound, "404 NOT FOUND: %s\n", c.Path)
-		})
+		if engine.RedirectFixedPath {
+			if location, ok := r.getFixedPath(c.Method, c.Host(), searchPath); ok {
+				c.handlers = append(c.handlers, redirect(location))
+				c.Next()
+				return
+			}
+		}
+		// 找不到路由时，由路径所在的最深的分组处理，分组的中间件同样会先执行
+		host = r.matchHost(c.Host())
+		c.handlers = engine.middlewares(host, c.Path)
+		if allowed := r.allowedMethods(c.Method, c.Host(), searchPath); len(allowed) > 0 {
+			c.SetHeader("Allow", strings.Join(allowed, ", "))
+			c.handlers = append(c.handlers, engine.noMethodHandlers(host, c.Path)...)
+		} else {
+			c.handlers = append(c.handlers, engine.noRouteHandlers(host, c.Path)...)
+		}
 	}
 	c.Next()
 }
 
+// matchHost 返回第一个匹配请求host的host模式，没有则返回空串，表示缺省host
+func (r *router) matchHost(host string) string {
+	host = stripHostPort(host)
+	for _, hr := range r.hosts {
+		if _, ok := hr.match(host); ok {
+			return hr.pattern
+		}
+	}
+	return ""
+}
+
+// allowedMethods 返回可以匹配path的其他方法，按字母序排列
+func (r *router) allowedMethods(method string, host string, path string) []string {
+	host = stripHostPort(host)
+	var roots []map[string]*node
+	for _, hr := range r.hosts {
+		if _, ok := hr.match(host); ok {
+			roots = append(roots, hr.roots)
+		}
+	}
+	roots = append(roots, r.roots)
+	var allowed []string
+	for _, root := range roots {
+		for m := range root {
+			if m == method || contains(allowed, m) {
+				continue
+			}
+			if n, _ := searchRoots(root, m, path); n != nil {
+				allowed = append(allowed, m)
+			}
+		}
+	}
+	sort.Strings(allowed)
+	return allowed
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
 func parsePattern(pattern string) []string {
 	vs := strings.Split(pattern, "/")
 
@@ -1,6 +1,8 @@
 package gee
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"testing"
 )
@@ -86,3 +88,258 @@ func TestRoute(t *testing.T) {
 		})
 	}
 }
+
+func TestHostRoute(t *testing.T) {
+	r := newTestRouter()
+	r.addHostRoute(":sub.example.com", "GET", "/hello/:name", nil)
+	r.addHostRoute("admin.example.com", "GET", "/dashboard", nil)
+
+	testCases := []struct {
+		desc      string
+		host      string
+		path      string
+		wantHost  string
+		want      string
+		wantParam map[string]string
+	}{
+		{
+			desc:      "子域名参数",
+			host:      "api.example.com:9999",
+			path:      "/hello/geektutu",
+			wantHost:  ":sub.example.com",
+			want:      "/hello/:name",
+			wantParam: map[string]string{"sub": "api", "name": "geektutu"},
+		},
+		{
+			desc:      "固定host",
+			host:      "Admin.Example.com",
+			path:      "/dashboard",
+			wantHost:  "admin.example.com",
+			want:      "/dashboard",
+			wantParam: map[string]string{},
+		},
+		{
+			desc:      "host下没有匹配路由时回退到缺省host",
+			host:      "admin.example.com",
+			path:      "/assets/a.css",
+			wantHost:  "",
+			want:      "/assets/*filepath",
+			wantParam: map[string]string{"filepath": "a.css"},
+		},
+		{
+			desc:      "未注册的host",
+			host:      "localhost",
+			path:      "/hello/geektutu",
+			wantHost:  "",
+			want:      "/hello/:name",
+			wantParam: map[string]string{"name": "geektutu"},
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			host, n, ps := r.getHostRoute("GET", tC.host, tC.path)
+			if n == nil || host != tC.wantHost || n.pattern != tC.want || !reflect.DeepEqual(ps, tC.wantParam) {
+				t.Errorf("getHostRoute(%s, %s) got host=%q node=%v params=%v, but we want host=%q pattern=%s params=%v",
+					tC.host, tC.path, host, n, ps, tC.wantHost, tC.want, tC.wantParam)
+			}
+		})
+	}
+}
+
+func TestHostMiddleware(t *testing.T) {
+	r := New()
+	var trace []string
+	r.Use(func(c *Context) { trace = append(trace, "engine") })
+	api := r.Host("api.example.com")
+	api.Use(func(c *Context) { trace = append(trace, "api") })
+	api.GET("/ping", func(c *Context) { c.Stringf(http.StatusOK, "api pong") })
+	r.GET("/ping", func(c *Context) { c.Stringf(http.StatusOK, "pong") })
+
+	testCases := []struct {
+		host  string
+		body  string
+		trace []string
+	}{
+		{host: "api.example.com", body: "api pong", trace: []string{"engine", "api"}},
+		{host: "www.example.com", body: "pong", trace: []string{"engine"}},
+	}
+	for _, tC := range testCases {
+		trace = nil
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "http://"+tC.host+"/ping", nil)
+		r.ServeHTTP(w, req)
+		if w.Body.String() != tC.body || !reflect.DeepEqual(trace, tC.trace) {
+			t.Errorf("host %s: got body %q and middlewares %v, but we want %q and %v", tC.host, w.Body.String(), trace, tC.body, tC.trace)
+		}
+	}
+}
+
+func TestParamConstraint(t *testing.T) {
+	r := newRouter()
+	r.addRoute("GET", "/user/:id{int}", nil)
+	r.addRoute("GET", "/user/:name", nil)
+	r.addRoute("GET", "/order/:no{[0-9]{3}}", nil)
+	r.addRoute("GET", "/token/:uuid{uuid}", nil)
+	r.addRoute("GET", "/post/:slug{slug}/edit", nil)
+
+	testCases := []struct {
+		desc  string
+		path  string
+		want  string
+		param map[string]string
+	}{
+		{
+			desc:  "满足int约束",
+			path:  "/user/42",
+			want:  "/user/:id{int}",
+			param: map[string]string{"id": "42"},
+		},
+		{
+			desc:  "不满足约束时交给其他路由",
+			path:  "/user/tom",
+			want:  "/user/:name",
+			param: map[string]string{"name": "tom"},
+		},
+		{
+			desc:  "正则约束",
+			path:  "/order/123",
+			want:  "/order/:no{[0-9]{3}}",
+			param: map[string]string{"no": "123"},
+		},
+		{
+			desc: "不满足正则约束时404",
+			path: "/order/1234",
+			want: "",
+		},
+		{
+			desc:  "uuid约束",
+			path:  "/token/123e4567-e89b-12d3-a456-426614174000",
+			want:  "/token/:uuid{uuid}",
+			param: map[string]string{"uuid": "123e4567-e89b-12d3-a456-426614174000"},
+		},
+		{
+			desc: "不满足slug约束时404",
+			path: "/post/Hello_World/edit",
+			want: "",
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			n, ps := r.getRoute("GET", tC.path)
+			if tC.want == "" {
+				if n != nil {
+					t.Errorf("getRoute(%s) got pattern=%s, but we want nil", tC.path, n.pattern)
+				}
+				return
+			}
+			if n == nil || n.pattern != tC.want || !reflect.DeepEqual(ps, tC.param) {
+				t.Errorf("getRoute(%s) got node=%v and params=%v, but we want pattern=%s and params=%v",
+					tC.path, n, ps, tC.want, tC.param)
+			}
+		})
+	}
+}
+
+func TestRouterOptions(t *testing.T) {
+	r := New()
+	r.RedirectTrailingSlash = true
+	r.RedirectFixedPath = true
+	r.UseRawPath = true
+	r.GET("/hello/:name", func(c *Context) { c.Stringf(http.StatusOK, "hello %s", c.Param("name")) })
+	r.GET("/docs/", func(c *Context) { c.Stringf(http.StatusOK, "docs") })
+	r.POST("/books", func(c *Context) { c.Stringf(http.StatusOK, "created") })
+	r.GET("/assets/*filepath", func(c *Context) { c.Stringf(http.StatusOK, "file %s", c.Param("filepath")) })
+
+	testCases := []struct {
+		desc     string
+		method   string
+		url      string
+		code     int
+		location string
+		body     string
+	}{
+		{desc: "多余的/", method: "GET", url: "/hello/geektutu/?a=1", code: 301, location: "/hello/geektutu?a=1"},
+		{desc: "缺少/", method: "GET", url: "/docs", code: 301, location: "/docs/"},
+		{desc: "非GET请求使用308", method: "POST", url: "/books/", code: 308, location: "/books"},
+		{desc: "清理路径", method: "GET", url: "/assets/../hello//geektutu", code: 301, location: "/hello/geektutu"},
+		{desc: "忽略大小写", method: "GET", url: "/HELLO/GeekTutu", code: 301, location: "/hello/GeekTutu"},
+		{desc: "*路由不重定向", method: "GET", url: "/assets/css/", code: 200, body: "file css"},
+		{desc: "转义的/作为参数的一部分", method: "GET", url: "/hello/a%2Fb", code: 200, body: "hello a/b"},
+		{desc: "无法修正", method: "GET", url: "/none", code: 404},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(tC.method, tC.url, nil))
+			if w.Code != tC.code || w.Header().Get("Location") != tC.location || (tC.body != "" && w.Body.String() != tC.body) {
+				t.Errorf("%s %s got code=%d location=%q body=%q, but we want code=%d location=%q body=%q",
+					tC.method, tC.url, w.Code, w.Header().Get("Location"), w.Body.String(), tC.code, tC.location, tC.body)
+			}
+		})
+	}
+}
+
+func TestRedirectToOtherHost(t *testing.T) {
+	r := New()
+	r.RedirectTrailingSlash = true
+	r.GET("/:name", func(c *Context) { c.Stringf(http.StatusOK, "hello %s", c.Param("name")) })
+	for _, url := range []string{"//evil.com/", "///evil.com/", "/\\evil.com/"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+		if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "/evil.com" {
+			t.Errorf("GET %s got code=%d location=%q, but we want code=301 location=\"/evil.com\"", url, w.Code, w.Header().Get("Location"))
+		}
+	}
+}
+
+func TestNoRoute(t *testing.T) {
+	r := New()
+	r.GET("/index", func(c *Context) { c.Stringf(http.StatusOK, "index") })
+	r.NoRoute(func(c *Context) { c.Stringf(http.StatusNotFound, "<h1>page not found</h1>") })
+	api := r.Group("/api")
+	api.Use(func(c *Context) { c.SetHeader("X-Api", "1") })
+	api.GET("/users", func(c *Context) { c.JSON(http.StatusOK, []string{}) })
+	api.POST("/users", func(c *Context) { c.JSON(http.StatusOK, nil) })
+	api.NoRoute(func(c *Context) { c.JSON(http.StatusNotFound, Obj{"error": "not found"}) })
+	api.NoMethod(func(c *Context) { c.JSON(http.StatusMethodNotAllowed, Obj{"error": "method not allowed"}) })
+	v1 := api.Group("/v1")
+	v1.GET("/ping", func(c *Context) { c.Stringf(http.StatusOK, "pong") })
+
+	testCases := []struct {
+		desc   string
+		method string
+		url    string
+		code   int
+		body   string
+		allow  string
+		api    bool
+	}{
+		{desc: "站点404", method: "GET", url: "/none", code: 404, body: "<h1>page not found</h1>"},
+		{desc: "站点405使用缺省处理", method: "POST", url: "/index", code: 405, body: "405 METHOD NOT ALLOWED: /index\n", allow: "GET"},
+		{desc: "api404", method: "GET", url: "/api/none", code: 404, body: `{"error":"not found"}` + "\n", api: true},
+		{desc: "api405", method: "DELETE", url: "/api/users", code: 405, body: `{"error":"method not allowed"}` + "\n", allow: "GET, POST", api: true},
+		{desc: "子分组继承api的处理", method: "GET", url: "/api/v1/none", code: 404, body: `{"error":"not found"}` + "\n", api: true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(tC.method, tC.url, nil))
+			if w.Code != tC.code || w.Body.String() != tC.body || w.Header().Get("Allow") != tC.allow {
+				t.Errorf("%s %s got code=%d body=%q allow=%q, but we want code=%d body=%q allow=%q",
+					tC.method, tC.url, w.Code, w.Body.String(), w.Header().Get("Allow"), tC.code, tC.body, tC.allow)
+			}
+			if (w.Header().Get("X-Api") == "1") != tC.api {
+				t.Errorf("%s %s api middleware should run: %v", tC.method, tC.url, tC.api)
+			}
+		})
+	}
+
+	// 没有设置NoRoute时使用缺省的文本404
+	w := httptest.NewRecorder()
+	New().ServeHTTP(w, httptest.NewRequest("GET", "/none", nil))
+	if w.Code != http.StatusNotFound || w.Body.String() != "404 NOT FOUND: /none\n" {
+		t.Errorf("default 404 got code=%d body=%q", w.Code, w.Body.String())
+	}
+}
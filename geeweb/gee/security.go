@@ -0,0 +1,206 @@
+package gee
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// CSRFOption 是CSRF中间件的配置，采用double-submit cookie：
+// token保存在cookie中，同时通过c.Get(ContextKey)交给模板渲染到表单（或者由前端放到请求头），
+// 非安全方法的请求必须在请求头或者表单字段中带上与cookie相同的token。
+type CSRFOption struct {
+	// Secret 不为空时，token使用HMAC签名，防止子域名等途径写入伪造的cookie
+	Secret     []byte
+	CookieName string
+	HeaderName string
+	FormField  string
+	// ContextKey 通过c.Get(ContextKey)获取当前请求的token
+	ContextKey string
+	CookiePath string
+	// Secure cookie只通过https发送
+	Secure bool
+	MaxAge int
+}
+
+var DefaultCSRFOption = CSRFOption{
+	CookieName: "_csrf",
+	HeaderName: "X-CSRF-Token",
+	FormField:  "_csrf",
+	ContextKey: "csrf_token",
+	CookiePath: "/",
+	MaxAge:     12 * 3600,
+}
+
+func parseCSRFOption(opt *CSRFOption) *CSRFOption {
+	o := DefaultCSRFOption
+	if opt == nil {
+		return &o
+	}
+	o.Secret, o.Secure = opt.Secret, opt.Secure
+	if opt.CookieName != "" {
+		o.CookieName = opt.CookieName
+	}
+	if opt.HeaderName != "" {
+		o.HeaderName = opt.HeaderName
+	}
+	if opt.FormField != "" {
+		o.FormField = opt.FormField
+	}
+	if opt.ContextKey != "" {
+		o.ContextKey = opt.ContextKey
+	}
+	if opt.CookiePath != "" {
+		o.CookiePath = opt.CookiePath
+	}
+	if opt.MaxAge != 0 {
+		o.MaxAge = opt.MaxAge
+	}
+	return &o
+}
+
+func (o *CSRFOption) sign(nonce string) string {
+	mac := hmac.New(sha256.New, o.Secret)
+	mac.Write([]byte(nonce))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// newToken 生成随机token，设置了Secret时，token的格式为nonce.signature
+func (o *CSRFOption) newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	if len(o.Secret) > 0 {
+		token += "." + o.sign(token)
+	}
+	return token, nil
+}
+
+func (o *CSRFOption) valid(token string) bool {
+	if token == "" {
+		return false
+	}
+	if len(o.Secret) == 0 {
+		return true
+	}
+	parts := strings.SplitN(token, ".", 2)
+	return len(parts) == 2 && hmac.Equal([]byte(parts[1]), []byte(o.sign(parts[0])))
+}
+
+// 安全方法不会修改服务端状态，无需校验token
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// CSRF 返回CSRF防护中间件，一般只施加在使用表单的分组上：
+// admin.Use(gee.CSRF(nil))
+// 模板中：<input type="hidden" name="_csrf" value="{{.csrf}}">，其中csrf通过c.Get("csrf_token")获取
+func CSRF(opt *CSRFOption) HandlerFunc {
+	o := parseCSRFOption(opt)
+	return func(c *Context) {
+		var cookieToken string
+		if cookie, err := c.Req.Cookie(o.CookieName); err == nil && o.valid(cookie.Value) {
+			cookieToken = cookie.Value
+		}
+		token := cookieToken
+		if token == "" {
+			var err error
+			if token, err = o.newToken(); err != nil {
+				c.Stringf(http.StatusInternalServerError, "500 INTERNAL SERVER ERROR: %v\n", err)
+				c.Abort()
+				return
+			}
+			http.SetCookie(c.Writer, &http.Cookie{
+				Name:     o.CookieName,
+				Value:    token,
+				Path:     o.CookiePath,
+				MaxAge:   o.MaxAge,
+				Secure:   o.Secure,
+				HttpOnly: true,
+				SameSite: http.SameSiteLaxMode,
+			})
+		}
+		c.Set(o.ContextKey, token)
+
+		if !isSafeMethod(c.Method) {
+			sent := c.Req.Header.Get(o.HeaderName)
+			if sent == "" {
+				sent = c.PostForm(o.FormField)
+			}
+			if cookieToken == "" || subtle.ConstantTimeCompare([]byte(sent), []byte(cookieToken)) != 1 {
+				c.Stringf(http.StatusForbidden, "403 FORBIDDEN: invalid csrf token\n")
+				c.Abort()
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// SecureHeadersOption 是安全响应头的配置，字段为空（或者0）表示不设置对应的响应头
+type SecureHeadersOption struct {
+	// HSTSMaxAge Strict-Transport-Security的max-age（秒），只在https请求中设置
+	HSTSMaxAge            int
+	HSTSIncludeSubdomains bool
+	// FrameOptions X-Frame-Options，例如DENY、SAMEORIGIN
+	FrameOptions          string
+	ContentSecurityPolicy string
+	ReferrerPolicy        string
+	// ContentTypeNosniff 设置X-Content-Type-Options: nosniff
+	ContentTypeNosniff bool
+}
+
+var DefaultSecureHeadersOption = SecureHeadersOption{
+	HSTSMaxAge:            365 * 24 * 3600,
+	HSTSIncludeSubdomains: true,
+	FrameOptions:          "DENY",
+	ContentSecurityPolicy: "default-src 'self'",
+	ReferrerPolicy:        "strict-origin-when-cross-origin",
+	ContentTypeNosniff:    true,
+}
+
+// SecureHeaders 返回设置安全响应头的中间件，opt为nil时使用DefaultSecureHeadersOption。
+// 不同分组可以使用不同的配置，例如嵌入第三方页面的分组放宽X-Frame-Options和CSP
+func SecureHeaders(opt *SecureHeadersOption) HandlerFunc {
+	if opt == nil {
+		opt = &DefaultSecureHeadersOption
+	}
+	o := *opt
+	hsts := ""
+	if o.HSTSMaxAge > 0 {
+		hsts = fmt.Sprintf("max-age=%d", o.HSTSMaxAge)
+		if o.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+	}
+	return func(c *Context) {
+		h := c.Writer.Header()
+		if hsts != "" && c.Scheme() == "https" {
+			h.Set("Strict-Transport-Security", hsts)
+		}
+		if o.FrameOptions != "" {
+			h.Set("X-Frame-Options", o.FrameOptions)
+		}
+		if o.ContentSecurityPolicy != "" {
+			h.Set("Content-Security-Policy", o.ContentSecurityPolicy)
+		}
+		if o.ReferrerPolicy != "" {
+			h.Set("Referrer-Policy", o.ReferrerPolicy)
+		}
+		if o.ContentTypeNosniff {
+			h.Set("X-Content-Type-Options", "nosniff")
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,116 @@
+package gee
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func newCSRFEngine(opt *CSRFOption) *Engine {
+	r := New()
+	admin := r.Group("/admin")
+	admin.Use(CSRF(opt))
+	admin.GET("/form", func(c *Context) {
+		token, _ := c.Get("csrf_token")
+		c.Stringf(http.StatusOK, "%v", token)
+	})
+	admin.POST("/form", func(c *Context) { c.Stringf(http.StatusOK, "saved") })
+	r.POST("/api", func(c *Context) { c.Stringf(http.StatusOK, "api") })
+	return r
+}
+
+func TestCSRF(t *testing.T) {
+	for _, opt := range []*CSRFOption{nil, {Secret: []byte("secret")}} {
+		r := newCSRFEngine(opt)
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/admin/form", nil))
+		cookies := w.Result().Cookies()
+		if w.Code != http.StatusOK || len(cookies) != 1 || cookies[0].Value != w.Body.String() {
+			t.Fatalf("GET should issue a csrf cookie equal to the token, got code=%d cookies=%v body=%q", w.Code, cookies, w.Body.String())
+		}
+		token := w.Body.String()
+
+		testCases := []struct {
+			desc   string
+			url    string
+			cookie string
+			header string
+			form   string
+			code   int
+		}{
+			{desc: "没有token", url: "/admin/form", cookie: token, code: 403},
+			{desc: "请求头中的token", url: "/admin/form", cookie: token, header: token, code: 200},
+			{desc: "表单中的token", url: "/admin/form", cookie: token, form: token, code: 200},
+			{desc: "token与cookie不一致", url: "/admin/form", cookie: token, header: token + "x", code: 403},
+			{desc: "没有cookie", url: "/admin/form", header: token, code: 403},
+			{desc: "其他分组不受影响", url: "/api", code: 200},
+		}
+		for _, tC := range testCases {
+			var req *http.Request
+			if tC.form != "" {
+				req = httptest.NewRequest("POST", tC.url, strings.NewReader(url.Values{"_csrf": {tC.form}}.Encode()))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			} else {
+				req = httptest.NewRequest("POST", tC.url, nil)
+			}
+			if tC.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "_csrf", Value: tC.cookie})
+			}
+			if tC.header != "" {
+				req.Header.Set("X-CSRF-Token", tC.header)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tC.code {
+				t.Errorf("%s: got code %d, but we want %d", tC.desc, w.Code, tC.code)
+			}
+		}
+	}
+
+	// 设置了Secret时，未签名的cookie无效
+	r := newCSRFEngine(&CSRFOption{Secret: []byte("secret")})
+	req := httptest.NewRequest("POST", "/admin/form", nil)
+	req.AddCookie(&http.Cookie{Name: "_csrf", Value: "forged"})
+	req.Header.Set("X-CSRF-Token", "forged")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("forged cookie got code %d, but we want 403", w.Code)
+	}
+}
+
+func TestSecureHeaders(t *testing.T) {
+	r := New()
+	r.Use(SecureHeaders(nil))
+	embed := r.Group("/embed")
+	embed.Use(SecureHeaders(&SecureHeadersOption{FrameOptions: "SAMEORIGIN"}))
+	r.GET("/page", func(c *Context) { c.Stringf(http.StatusOK, "page") })
+	embed.GET("/widget", func(c *Context) { c.Stringf(http.StatusOK, "widget") })
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/page", nil)
+	req.TLS = &tls.ConnectionState{}
+	r.ServeHTTP(w, req)
+	want := map[string]string{
+		"Strict-Transport-Security": "max-age=31536000; includeSubDomains",
+		"X-Frame-Options":           "DENY",
+		"Content-Security-Policy":   "default-src 'self'",
+		"Referrer-Policy":           "strict-origin-when-cross-origin",
+		"X-Content-Type-Options":    "nosniff",
+	}
+	for k, v := range want {
+		if got := w.Header().Get(k); got != v {
+			t.Errorf("header %s = %q, but we want %q", k, got, v)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/embed/widget", nil))
+	if w.Header().Get("X-Frame-Options") != "SAMEORIGIN" || w.Header().Get("Strict-Transport-Security") != "" {
+		t.Errorf("group option should override X-Frame-Options and HSTS needs https, got %v", w.Header())
+	}
+}
@@ -1,18 +1,58 @@
 package gee
 
-import "strings"
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
 
 type node struct {
-	pattern  string  // 待匹配路由，例如 /p/:lang
-	part     string  // 路由中的一部分，例如 :lang
-	children []*node // 子节点，例如 [doc, tutorial, intro]
-	isWild   bool    // 是否精确匹配，part 含有 : 或 * 时为true
+	pattern    string         // 待匹配路由，例如 /p/:lang
+	part       string         // 路由中的一部分，例如 :lang
+	children   []*node        // 子节点，例如 [doc, tutorial, intro]
+	isWild     bool           // 是否精确匹配，part 含有 : 或 * 时为true
+	constraint *regexp.Regexp // 参数的约束，例如 :id{[0-9]+} 或者 :id{int}，为nil表示不限制
+}
+
+// 内置的参数类型，:id{int}等价于:id{-?[0-9]+}
+var paramTypes = map[string]string{
+	"int":  `-?[0-9]+`,
+	"uuid": `[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`,
+	"slug": `[a-z0-9]+(?:-[a-z0-9]+)*`,
+}
+
+// paramName 返回参数名，例如 :id{int} 的参数名为id
+func paramName(part string) string {
+	name := part[1:]
+	if i := strings.IndexByte(name, '{'); i >= 0 {
+		return name[:i]
+	}
+	return name
+}
+
+// parseConstraint 解析参数的约束，约束为{}之间的正则表达式或者内置类型，必须匹配整个路径片段。
+// 注意路由按/分割，因此约束中不能出现/
+func parseConstraint(part string) *regexp.Regexp {
+	start := strings.IndexByte(part, '{')
+	if part[0] != ':' || start < 0 || !strings.HasSuffix(part, "}") {
+		return nil
+	}
+	expr := part[start+1 : len(part)-1]
+	if typ, ok := paramTypes[expr]; ok {
+		expr = typ
+	}
+	re, err := regexp.Compile("^(?:" + expr + ")$")
+	if err != nil {
+		panic(fmt.Sprintf("invalid constraint in route part %s: %v", part, err))
+	}
+	return re
 }
 
 // 第一个匹配成功的节点，用于插入
+// 插入时要求part完全一致，否则:id{int}和:name这样约束不同的参数会被合并到同一个节点
 func (n *node) matchChild(part string) *node {
 	for _, child := range n.children {
-		if child.part == part || child.isWild {
+		if child.part == part {
 			return child
 		}
 	}
@@ -20,10 +60,11 @@ func (n *node) matchChild(part string) *node {
 }
 
 // 所有匹配成功的节点，用于查找
+// 带约束的参数节点只匹配满足约束的片段，不满足的交给其他兄弟节点，最终都不匹配时返回404
 func (n *node) matchChildren(part string) []*node {
 	nodes := make([]*node, 0)
 	for _, child := range n.children {
-		if child.part == part || child.isWild {
+		if child.part == part || (child.isWild && (child.constraint == nil || child.constraint.MatchString(part))) {
 			nodes = append(nodes, child)
 		}
 	}
@@ -40,7 +81,7 @@ func (n *node) insert(pattern string, parts []string, height int) {
 	part := parts[height]
 	child := n.matchChild(part)
 	if child == nil {
-		child = &node{part: part, isWild: part[0] == ':' || part[0] == '*'}
+		child = &node{part: part, isWild: part[0] == ':' || part[0] == '*', constraint: parseConstraint(part)}
 		n.children = append(n.children, child)
 	}
 	child.insert(pattern, parts, height+1)
@@ -66,3 +107,25 @@ func (n *node) search(parts []string, height int) *node {
 
 	return nil
 }
+
+// searchFold 与search类似，但静态部分忽略大小写，用于修正路径
+func (n *node) searchFold(parts []string, height int) *node {
+	if len(parts) == height || strings.HasPrefix(n.part, "*") {
+		if n.pattern == "" {
+			return nil
+		}
+		return n
+	}
+
+	part := parts[height]
+	for _, child := range n.children {
+		if !strings.EqualFold(child.part, part) && !(child.isWild && (child.constraint == nil || child.constraint.MatchString(part))) {
+			continue
+		}
+		if result := child.searchFold(parts, height+1); result != nil {
+			return result
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,195 @@
+package gee
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// VersionOption 指定除了URL前缀之外，客户端还可以怎样选择API版本
+type VersionOption struct {
+	// Vendor 非空时，支持通过Accept选择版本，例如Vendor为x时：Accept: application/vnd.x.v2+json
+	Vendor string
+	// Header 非空时，支持通过该请求头选择版本，例如API-Version: v2（或者2），响应中也会带上实际使用的版本
+	Header string
+	// Default 没有指定版本时使用的版本，为空时使用最新的版本
+	Default string
+}
+
+// Versions 管理一个分组下的多个API版本，每个版本是分组下以版本号为前缀的子分组，例如/api/v1、/api/v2。
+// 请求的版本没有对应的路由时，回退到不高于它的最新版本，因此新版本只需要注册有变化的路由：
+// vs := r.Group("/api").Versions(&gee.VersionOption{Vendor: "x", Header: "API-Version"})
+// v1 := vs.Version("v1")
+// v1.GET("/users", listUsers)
+// v1.GET("/orders", listOrdersV1)
+// v2 := vs.Version("v2")
+// v2.GET("/orders", listOrdersV2)
+// 此时/api/v2/users由v1处理；/api/orders根据Accept或者API-Version选择版本，都没有时使用v2
+type Versions struct {
+	group    *RouterGroup
+	opt      VersionOption
+	accept   *regexp.Regexp
+	versions []*apiVersion // 按版本号从低到高排列
+}
+
+type apiVersion struct {
+	name   string
+	number []int
+	group  *RouterGroup
+}
+
+// Versions 在当前分组下创建版本管理，opt为nil时只支持通过URL前缀选择版本
+func (group *RouterGroup) Versions(opt *VersionOption) *Versions {
+	vs := &Versions{group: group}
+	if opt != nil {
+		vs.opt = *opt
+	}
+	if vs.opt.Vendor != "" {
+		vs.accept = regexp.MustCompile(`application/vnd\.` + regexp.QuoteMeta(vs.opt.Vendor) + `\.(v[0-9.]+)(\+|;|,|$)`)
+	}
+	group.engine.versions = append(group.engine.versions, vs)
+	return vs
+}
+
+// Version 返回名称为name的版本对应的分组，不存在则创建。name形如v1、v2.1
+func (vs *Versions) Version(name string) *RouterGroup {
+	if v := vs.find(name); v != nil {
+		return v.group
+	}
+	number, ok := parseVersion(name)
+	if !ok {
+		panic(fmt.Sprintf("gee: invalid api version %q, want the form v1 or v2.1", name))
+	}
+	v := &apiVersion{name: name, number: number, group: vs.group.Group("/" + name)}
+	vs.versions = append(vs.versions, v)
+	sort.Slice(vs.versions, func(i, j int) bool {
+		return compareVersion(vs.versions[i].number, vs.versions[j].number) < 0
+	})
+	return v.group
+}
+
+// Deprecate 将版本标记为废弃，该版本处理的请求（包括回退到该版本的请求）会带上Deprecation头，
+// sunset不为零值时还会带上Sunset头，告知客户端该版本下线的时间
+func (vs *Versions) Deprecate(name string, sunset time.Time) {
+	group := vs.Version(name)
+	group.Use(func(c *Context) {
+		c.SetHeader("Deprecation", "true")
+		if !sunset.IsZero() {
+			c.SetHeader("Sunset", sunset.UTC().Format(http.TimeFormat))
+		}
+		if latest := vs.versions[len(vs.versions)-1]; latest.name != name {
+			c.SetHeader("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, latest.group.prefix))
+		}
+		c.Next()
+	})
+}
+
+func (vs *Versions) find(name string) *apiVersion {
+	for _, v := range vs.versions {
+		if v.name == name {
+			return v
+		}
+	}
+	return nil
+}
+
+// rewrite 在路由之前确定请求使用的版本，并将c.Path改写为该版本下的路径，返回请求是否属于该分组
+func (vs *Versions) rewrite(c *Context) bool {
+	base := vs.group.prefix
+	if len(vs.versions) == 0 || !strings.HasPrefix(c.Path, base+"/") {
+		return false
+	}
+	rest := c.Path[len(base):]
+	requested, explicit := vs.requested(c)
+	// 路径中的版本优先于Accept和请求头
+	segment := strings.SplitN(rest[1:], "/", 2)
+	if number, ok := parseVersion(segment[0]); ok {
+		requested, explicit = number, true
+		rest = strings.TrimPrefix(rest[1:], segment[0])
+	}
+	if requested == nil && vs.opt.Default != "" {
+		requested, _ = parseVersion(vs.opt.Default)
+	}
+
+	for i := len(vs.versions) - 1; i >= 0; i-- {
+		v := vs.versions[i]
+		if requested != nil && compareVersion(v.number, requested) > 0 {
+			continue
+		}
+		p := base + "/" + v.name + rest
+		if _, n, _ := c.engine.router.getHostRoute(c.Method, c.Host(), p); n == nil {
+			continue
+		}
+		if vs.opt.Header != "" {
+			c.SetHeader(vs.opt.Header, v.name)
+		}
+		if p != c.Path {
+			c.setPath(p)
+		}
+		return true
+	}
+	// 指定的版本不存在时保持原路径，交给NoRoute处理
+	return explicit
+}
+
+// requested 从Accept和请求头中解析客户端要求的版本
+func (vs *Versions) requested(c *Context) ([]int, bool) {
+	if vs.accept != nil {
+		if m := vs.accept.FindStringSubmatch(c.Req.Header.Get("Accept")); m != nil {
+			if number, ok := parseVersion(m[1]); ok {
+				return number, true
+			}
+		}
+	}
+	if vs.opt.Header != "" {
+		if value := strings.TrimSpace(c.Req.Header.Get(vs.opt.Header)); value != "" {
+			if !strings.HasPrefix(value, "v") {
+				value = "v" + value
+			}
+			if number, ok := parseVersion(value); ok {
+				return number, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// parseVersion 解析v1、v2.1这样的版本号
+func parseVersion(name string) ([]int, bool) {
+	if len(name) < 2 || name[0] != 'v' {
+		return nil, false
+	}
+	var number []int
+	for _, part := range strings.Split(name[1:], ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return nil, false
+		}
+		number = append(number, n)
+	}
+	return number, true
+}
+
+// compareVersion 逐段比较版本号，缺少的段视为0，即v2等于v2.0
+func compareVersion(a, b []int) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
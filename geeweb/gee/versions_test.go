@@ -0,0 +1,62 @@
+package gee
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestVersions(t *testing.T) {
+	r := New()
+	vs := r.Group("/api").Versions(&VersionOption{Vendor: "x", Header: "API-Version"})
+	v1 := vs.Version("v1")
+	v1.GET("/users", func(c *Context) { c.Stringf(http.StatusOK, "users v1 %s", c.Path) })
+	v1.GET("/orders", func(c *Context) { c.Stringf(http.StatusOK, "orders v1") })
+	v2 := vs.Version("v2")
+	v2.GET("/orders", func(c *Context) { c.Stringf(http.StatusOK, "orders v2") })
+	vs.Version("v2.1").GET("/orders/:id", func(c *Context) { c.Stringf(http.StatusOK, "order v2.1 %s", c.Param("id")) })
+	vs.Deprecate("v1", time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	testCases := []struct {
+		desc       string
+		url        string
+		headers    map[string]string
+		code       int
+		body       string
+		version    string
+		deprecated bool
+	}{
+		{desc: "URL前缀", url: "/api/v2/orders", code: 200, body: "orders v2", version: "v2"},
+		{desc: "回退到v1", url: "/api/v2/users", code: 200, body: "users v1 /api/v1/users", version: "v1", deprecated: true},
+		{desc: "缺省使用最新版本", url: "/api/orders", code: 200, body: "orders v2", version: "v2"},
+		{desc: "Accept", url: "/api/orders", headers: map[string]string{"Accept": "application/vnd.x.v1+json"},
+			code: 200, body: "orders v1", version: "v1", deprecated: true},
+		{desc: "请求头", url: "/api/orders", headers: map[string]string{"API-Version": "2"}, code: 200, body: "orders v2", version: "v2"},
+		{desc: "不会使用更高的版本", url: "/api/v2/orders/1", code: 404},
+		{desc: "次版本号", url: "/api/v2.1/orders/1", code: 200, body: "order v2.1 1", version: "v2.1"},
+		{desc: "不存在的版本回退", url: "/api/v3/orders", code: 200, body: "orders v2", version: "v2"},
+		{desc: "不存在的路由同样执行分组的中间件", url: "/api/v1/none", code: 404, deprecated: true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tC.url, nil)
+			for k, v := range tC.headers {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tC.code || (tC.body != "" && w.Body.String() != tC.body) || w.Header().Get("API-Version") != tC.version {
+				t.Errorf("%s got code=%d body=%q version=%q, but we want code=%d body=%q version=%q",
+					tC.url, w.Code, w.Body.String(), w.Header().Get("API-Version"), tC.code, tC.body, tC.version)
+			}
+			if deprecated := w.Header().Get("Deprecation") == "true"; deprecated != tC.deprecated {
+				t.Errorf("%s got deprecated=%v, but we want %v", tC.url, deprecated, tC.deprecated)
+			}
+			if tC.deprecated && (w.Header().Get("Sunset") != "Tue, 01 Jan 2030 00:00:00 GMT" ||
+				w.Header().Get("Link") != `</api/v2.1>; rel="successor-version"`) {
+				t.Errorf("%s got sunset=%q link=%q", tC.url, w.Header().Get("Sunset"), w.Header().Get("Link"))
+			}
+		})
+	}
+}
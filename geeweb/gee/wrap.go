@@ -0,0 +1,92 @@
+package gee
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// statusWriter 记录被包装的http.Handler写入的状态码，这样Logger等中间件也能从c.StatusCode拿到
+// 同时透传Hijack和Flush，geerpc.Server的CONNECT隧道需要Hijack
+type statusWriter struct {
+	http.ResponseWriter
+	c *Context
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	w.c.StatusCode = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.c.StatusCode == 0 {
+		w.c.StatusCode = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("gee: response writer does not support hijack")
+	}
+	return h.Hijack()
+}
+
+// WrapH 将http.Handler转换为HandlerFunc，这样geerpc.Server、geecache.HTTPPool、pprof等已有的Handler
+// 就可以直接注册为gee的路由，并且享受分组中间件
+func WrapH(h http.Handler) HandlerFunc {
+	return func(c *Context) {
+		h.ServeHTTP(&statusWriter{ResponseWriter: c.Writer, c: c}, c.Req)
+	}
+}
+
+// WrapF 将http.HandlerFunc转换为HandlerFunc
+func WrapF(f http.HandlerFunc) HandlerFunc {
+	return WrapH(f)
+}
+
+// Mount 将handler挂载到prefix下，prefix及其下所有路径、所有方法的请求都交给handler处理，
+// 交给handler之前去掉分组前缀和prefix，分组的中间件照常执行。
+// handler也可以是另外一个*gee.Engine，这样就可以把一个独立的应用作为子应用挂载进来：
+// admin := gee.New()
+// admin.GET("/users", listUsers)
+// engine.Mount("/admin", admin) // 访问/admin/users
+func (group *RouterGroup) Mount(prefix string, handler http.Handler) {
+	absolutePath := path.Join(group.prefix, prefix)
+	h := WrapH(stripPrefix(absolutePath, handler))
+	group.Any(prefix, h)
+	group.Any(path.Join(prefix, "/*mountpath"), h)
+}
+
+// stripPrefix 与http.StripPrefix类似，但保证去掉前缀后的路径仍然以/开头
+func stripPrefix(prefix string, h http.Handler) http.Handler {
+	strip := func(p string) string {
+		p = strings.TrimPrefix(p, prefix)
+		if !strings.HasPrefix(p, "/") {
+			p = "/" + p
+		}
+		return p
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r2 := new(http.Request)
+		*r2 = *req
+		r2.URL = new(url.URL)
+		*r2.URL = *req.URL
+		r2.URL.Path = strip(req.URL.Path)
+		if req.URL.RawPath != "" {
+			r2.URL.RawPath = strip(req.URL.RawPath)
+		}
+		h.ServeHTTP(w, r2)
+	})
+}
@@ -0,0 +1,57 @@
+package gee
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestMount(t *testing.T) {
+	var trace []string
+	sub := New()
+	sub.Use(func(c *Context) { trace = append(trace, "sub") })
+	sub.GET("/users/:id", func(c *Context) { c.Stringf(http.StatusOK, "user %s", c.Param("id")) })
+	sub.GET("/", func(c *Context) { c.Stringf(http.StatusOK, "index") })
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprintf(w, "mux %s %s", req.Method, req.URL.Path)
+	})
+
+	r := New()
+	var status int
+	r.Use(func(c *Context) {
+		c.Next()
+		status = c.StatusCode
+	})
+	admin := r.Group("/admin")
+	admin.Use(func(c *Context) { trace = append(trace, "admin") })
+	admin.Mount("/app", sub)
+	r.Mount("/mux", mux)
+	r.GET("/f", WrapF(func(w http.ResponseWriter, req *http.Request) { fmt.Fprint(w, "f") }))
+
+	testCases := []struct {
+		method string
+		url    string
+		code   int
+		body   string
+		trace  []string
+	}{
+		{method: "GET", url: "/admin/app/users/1", code: 200, body: "user 1", trace: []string{"admin", "sub"}},
+		{method: "GET", url: "/admin/app", code: 200, body: "index", trace: []string{"admin", "sub"}},
+		{method: "DELETE", url: "/mux/a/b", code: 202, body: "mux DELETE /a/b"},
+		{method: "GET", url: "/f", code: 200, body: "f"},
+	}
+	for _, tC := range testCases {
+		trace, status = nil, 0
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(tC.method, tC.url, nil))
+		if w.Code != tC.code || status != tC.code || w.Body.String() != tC.body || !reflect.DeepEqual(trace, tC.trace) {
+			t.Errorf("%s %s got code=%d status=%d body=%q trace=%v, but we want code=%d body=%q trace=%v",
+				tC.method, tC.url, w.Code, status, w.Body.String(), trace, tC.code, tC.body, tC.trace)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"geektutu/geeorm"
+	"geektutu/geerpc"
+)
+
+// DB 检查geeorm.Engine的数据库连接
+func DB(engine *geeorm.Engine) Checker {
+	return CheckerFunc(engine.Ping)
+}
+
+// RPC 检查geerpc服务，rpcAddr的格式与geerpc.XDial相同，例如tcp@10.0.0.1:9999、http@10.0.0.1:9999。
+// 建立连接后调用一个不存在的服务，服务端返回了geerpc.ServerError说明它能正常处理请求
+func RPC(rpcAddr string) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		opt := geerpc.DefaultOption
+		if deadline, ok := ctx.Deadline(); ok {
+			opt.ConnectTimeout = time.Until(deadline)
+		}
+		client, err := geerpc.XDial(rpcAddr, &opt)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = client.Close() }()
+		var reply struct{}
+		err = client.Call(ctx, "_health.Ping", struct{}{}, &reply)
+		var serverErr geerpc.ServerError
+		if err != nil && !errors.As(err, &serverErr) {
+			return err
+		}
+		return nil
+	})
+}
+
+// CachePeer 检查geecache的节点，peerURL是节点HTTPPool的地址，例如http://10.0.0.1:8001/_geecache/，
+// 节点返回5xx之外的响应都视为可用
+func CachePeer(peerURL string) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, peerURL, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return fmt.Errorf("cache peer returned %s", resp.Status)
+		}
+		return nil
+	})
+}
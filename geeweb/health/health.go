@@ -0,0 +1,221 @@
+// Package health 提供可以挂载到gee的健康检查：/healthz表示进程存活，/readyz执行注册的检查，
+// 报告依赖（数据库、rpc服务、缓存节点等）是否可用，供编排系统决定是否把流量转发给该实例。
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"geektutu/geeweb/gee"
+)
+
+const (
+	StatusUp       = "up"
+	StatusDown     = "down"
+	StatusDegraded = "degraded" // 只有非关键的检查失败
+)
+
+// Checker 检查某个依赖是否可用，ctx带有检查的超时时间
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc 将函数转换为Checker
+type CheckerFunc func(ctx context.Context) error
+
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// CheckOption 是单个检查的配置
+type CheckOption struct {
+	// Timeout 单次检查的超时时间，超时视为失败
+	Timeout time.Duration
+	// CacheTTL 检查结果的缓存时间，避免频繁的探测压垮依赖，为负数时不缓存
+	CacheTTL time.Duration
+	// Critical 关键的检查失败时，实例未就绪（503）；非关键的检查失败时，只报告为degraded
+	Critical bool
+}
+
+// DefaultCheckOption 是Register的opt为nil时使用的配置
+var DefaultCheckOption = &CheckOption{
+	Timeout:  3 * time.Second,
+	CacheTTL: 5 * time.Second,
+	Critical: true,
+}
+
+func parseCheckOption(opt *CheckOption) CheckOption {
+	if opt == nil {
+		return *DefaultCheckOption
+	}
+	o := *opt
+	if o.Timeout <= 0 {
+		o.Timeout = DefaultCheckOption.Timeout
+	}
+	if o.CacheTTL == 0 {
+		o.CacheTTL = DefaultCheckOption.CacheTTL
+	}
+	return o
+}
+
+// Result 是单个检查的结果
+type Result struct {
+	Status    string    `json:"status"`
+	Critical  bool      `json:"critical"`
+	Error     string    `json:"error,omitempty"`
+	Duration  string    `json:"duration"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Report 是所有检查的汇总结果
+type Report struct {
+	Status string             `json:"status"`
+	Checks map[string]*Result `json:"checks,omitempty"`
+}
+
+type check struct {
+	name    string
+	checker Checker
+	opt     CheckOption
+
+	mu     sync.Mutex // 同一时刻只执行一次检查，并发的请求等待并共享结果
+	last   *Result
+	expire time.Time
+}
+
+// Registry 是具名检查的集合
+type Registry struct {
+	mu     sync.RWMutex
+	checks map[string]*check
+}
+
+func NewRegistry() *Registry {
+	return &Registry{checks: make(map[string]*check)}
+}
+
+// Register 注册一个检查，同名的检查会被替换，opt为nil时使用DefaultCheckOption
+func (r *Registry) Register(name string, checker Checker, opt *CheckOption) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks[name] = &check{name: name, checker: checker, opt: parseCheckOption(opt)}
+}
+
+// Unregister 删除一个检查
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.checks, name)
+}
+
+// Check 并发执行所有检查，并汇总结果
+func (r *Registry) Check(ctx context.Context) *Report {
+	r.mu.RLock()
+	checks := make([]*check, 0, len(r.checks))
+	for _, c := range r.checks {
+		checks = append(checks, c)
+	}
+	r.mu.RUnlock()
+	sort.Slice(checks, func(i, j int) bool { return checks[i].name < checks[j].name })
+
+	results := make([]*Result, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c *check) {
+			defer wg.Done()
+			results[i] = c.run(ctx)
+		}(i, c)
+	}
+	wg.Wait()
+
+	report := &Report{Status: StatusUp, Checks: make(map[string]*Result, len(checks))}
+	for i, c := range checks {
+		report.Checks[c.name] = results[i]
+		if results[i].Status == StatusUp {
+			continue
+		}
+		if c.opt.Critical {
+			report.Status = StatusDown
+		} else if report.Status == StatusUp {
+			report.Status = StatusDegraded
+		}
+	}
+	return report
+}
+
+// run 执行检查，缓存未过期时直接返回上一次的结果
+func (c *check) run(ctx context.Context) *Result {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if c.last != nil && now.Before(c.expire) {
+		return c.last
+	}
+
+	parent := ctx
+	ctx, cancel := context.WithTimeout(parent, c.opt.Timeout)
+	defer cancel()
+	// Checker不一定遵守ctx，因此在单独的goroutine中执行，超时后直接返回
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				done <- fmt.Errorf("panic: %v", p)
+			}
+		}()
+		done <- c.checker.Check(ctx)
+	}()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("check timeout: expect within %s", c.opt.Timeout)
+	}
+
+	result := &Result{
+		Status:    StatusUp,
+		Critical:  c.opt.Critical,
+		Duration:  time.Since(now).String(),
+		CheckedAt: now,
+	}
+	if err != nil {
+		result.Status, result.Error = StatusDown, err.Error()
+	}
+	// 调用方取消导致的失败（比如客户端断开连接）不代表依赖的状态，不缓存
+	if parent.Err() == nil {
+		c.last, c.expire = result, now.Add(c.opt.CacheTTL)
+	}
+	return result
+}
+
+// Liveness 返回存活检查的handler，只要进程能够处理请求就返回200，不执行任何检查，
+// 避免依赖故障时编排系统反复重启实例
+func (r *Registry) Liveness() gee.HandlerFunc {
+	return func(c *gee.Context) {
+		c.JSON(http.StatusOK, &Report{Status: StatusUp})
+	}
+}
+
+// Readiness 返回就绪检查的handler，执行所有检查，关键的检查失败时返回503
+func (r *Registry) Readiness() gee.HandlerFunc {
+	return func(c *gee.Context) {
+		report := r.Check(c)
+		code := http.StatusOK
+		if report.Status == StatusDown {
+			code = http.StatusServiceUnavailable
+		}
+		c.SetHeader("Cache-Control", "no-store")
+		c.JSON(code, report)
+	}
+}
+
+// Mount 在分组下注册GET /healthz和GET /readyz
+// health.NewRegistry().Mount(r.Group(""))
+func (r *Registry) Mount(group *gee.RouterGroup) {
+	group.GET("/healthz", r.Liveness())
+	group.GET("/readyz", r.Readiness())
+}
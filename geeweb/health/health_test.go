@@ -0,0 +1,102 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"geektutu/geecache"
+	"geektutu/geerpc"
+	"geektutu/geeweb/gee"
+)
+
+func TestRegistry(t *testing.T) {
+	var calls int32
+	r := NewRegistry()
+	r.Register("db", CheckerFunc(func(ctx context.Context) error {
+		atomic.AddInt32(&calls, 1)
+		return nil
+	}), nil)
+	r.Register("search", CheckerFunc(func(ctx context.Context) error {
+		return errors.New("search is down")
+	}), &CheckOption{Critical: false})
+	engine := gee.New()
+	r.Mount(engine.Group(""))
+
+	get := func(path string) (int, *Report) {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		var report Report
+		if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+			t.Fatalf("GET %s: invalid json %q", path, w.Body.String())
+		}
+		return w.Code, &report
+	}
+
+	if code, report := get("/healthz"); code != http.StatusOK || report.Status != StatusUp || len(report.Checks) != 0 {
+		t.Fatalf("liveness got code=%d report=%+v", code, report)
+	}
+	code, report := get("/readyz")
+	if code != http.StatusOK || report.Status != StatusDegraded {
+		t.Fatalf("non-critical failure should be degraded, got code=%d status=%s", code, report.Status)
+	}
+	if search := report.Checks["search"]; search.Status != StatusDown || search.Error != "search is down" || search.Critical {
+		t.Fatalf("unexpected search result %+v", search)
+	}
+	get("/readyz")
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("result should be cached, but checker was called %d times", n)
+	}
+
+	r.Register("rpc", CheckerFunc(func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	}), &CheckOption{Timeout: 50 * time.Millisecond, Critical: true})
+	code, report = get("/readyz")
+	if code != http.StatusServiceUnavailable || report.Status != StatusDown || report.Checks["rpc"].Status != StatusDown {
+		t.Fatalf("critical timeout should be down, got code=%d report=%+v", code, report)
+	}
+}
+
+type Foo int
+
+func (f Foo) Sum(args [2]int, reply *int) error {
+	*reply = args[0] + args[1]
+	return nil
+}
+
+func TestCheckers(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	server := geerpc.NewServer()
+	var foo Foo
+	_ = server.Register(&foo)
+	l, _ := net.Listen("tcp", "127.0.0.1:0")
+	go server.Accept(l)
+	defer l.Close()
+	if err := RPC("tcp@" + l.Addr().String()).Check(ctx); err != nil {
+		t.Errorf("rpc checker: %v", err)
+	}
+
+	peers := geecache.NewHTTPPool("http://127.0.0.1")
+	ts := httptest.NewServer(peers)
+	if err := CachePeer(ts.URL + "/_geecache/").Check(ctx); err != nil {
+		t.Errorf("cache peer checker: %v", err)
+	}
+	ts.Close()
+	for name, checker := range map[string]Checker{
+		"rpc":        RPC("tcp@" + ts.Listener.Addr().String()),
+		"cache peer": CachePeer(ts.URL + "/_geecache/"),
+	} {
+		if err := checker.Check(ctx); err == nil {
+			t.Errorf("%s checker should fail after the server is closed", name)
+		}
+	}
+}
@@ -0,0 +1,331 @@
+// Package rest 为geeorm的模型在gee上生成REST风格的增删改查接口：
+//
+// users := r.Group("/users")
+// rest.Register(users, engine, &User{}, &rest.Option{Filters: []string{"Name"}, Orders: []string{"Age"}})
+//
+// GET    /users?page=1&size=20&Name=Tom&order=-Age  分页列出记录
+// GET    /users/:id                                  获取一条记录
+// POST   /users                                      创建记录
+// PUT    /users/:id                                  更新记录
+// DELETE /users/:id                                  删除记录
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"geektutu/geeorm"
+	"geektutu/geeorm/schema"
+	"geektutu/geeorm/session"
+	"geektutu/geeweb/gee"
+)
+
+// Option 控制列表接口允许的查询条件
+type Option struct {
+	// Filters 允许作为查询参数过滤的字段，多个条件之间是AND的关系，例如?Name=Tom&Age=18
+	Filters []string
+	// Orders 允许排序的字段，例如?order=-Age,Name表示按Age降序、Name升序
+	Orders []string
+	// DefaultPageSize 没有指定size时每页的记录数
+	DefaultPageSize int
+	// MaxPageSize 每页最多的记录数
+	MaxPageSize int
+}
+
+var DefaultOption = &Option{
+	DefaultPageSize: 20,
+	MaxPageSize:     100,
+}
+
+// Page 是列表接口的响应
+type Page struct {
+	Items interface{} `json:"items"`
+	Page  int         `json:"page"`
+	Size  int         `json:"size"`
+	Total int64       `json:"total"`
+}
+
+type resource struct {
+	engine  *geeorm.Engine
+	model   reflect.Type
+	table   *schema.Schema
+	pk      string
+	filters map[string]bool
+	orders  map[string]bool
+	opt     Option
+}
+
+// Register 在group下为model注册增删改查的路由，model是结构体或者指向结构体的指针。
+// 主键是geeorm标签中包含PRIMARY KEY的字段，没有时使用名为ID的字段。
+// 创建和更新时，请求体以JSON绑定到model，model实现了gee.Validator时会先进行校验。
+// 与Insert一样，主键需要由客户端提供，或者在BeforeInsert钩子中生成
+func Register(group *gee.RouterGroup, engine *geeorm.Engine, model interface{}, opt *Option) error {
+	typ := reflect.Indirect(reflect.ValueOf(model)).Type()
+	if typ.Kind() != reflect.Struct {
+		return fmt.Errorf("rest: model should be a struct, but got %s", typ)
+	}
+	table := engine.NewSession().Model(reflect.New(typ).Interface()).RefTable()
+	r := &resource{
+		engine:  engine,
+		model:   typ,
+		table:   table,
+		pk:      primaryKey(table),
+		filters: make(map[string]bool),
+		orders:  make(map[string]bool),
+		opt:     *DefaultOption,
+	}
+	if r.pk == "" {
+		return fmt.Errorf("rest: model %s has no primary key", typ.Name())
+	}
+	if opt != nil {
+		if opt.DefaultPageSize > 0 {
+			r.opt.DefaultPageSize = opt.DefaultPageSize
+		}
+		if opt.MaxPageSize > 0 {
+			r.opt.MaxPageSize = opt.MaxPageSize
+		}
+		for _, name := range opt.Filters {
+			if table.GetField(name) == nil {
+				return fmt.Errorf("rest: filter field %s doesn't exist in %s", name, typ.Name())
+			}
+			r.filters[name] = true
+		}
+		for _, name := range opt.Orders {
+			if table.GetField(name) == nil {
+				return fmt.Errorf("rest: order field %s doesn't exist in %s", name, typ.Name())
+			}
+			r.orders[name] = true
+		}
+	}
+
+	// 集合使用分组本身的路径/users，而不是/users/，开启RedirectTrailingSlash时也不会被重定向
+	group.GET("", r.list)
+	group.POST("", r.create)
+	group.GET("/:id", r.get)
+	group.PUT("/:id", r.update)
+	group.DELETE("/:id", r.delete)
+	return nil
+}
+
+func primaryKey(table *schema.Schema) string {
+	for _, field := range table.Fields {
+		if strings.Contains(strings.ToUpper(field.Tag), "PRIMARY KEY") {
+			return field.Name
+		}
+	}
+	if table.GetField("ID") != nil {
+		return "ID"
+	}
+	return ""
+}
+
+// session 返回绑定到该模型的session，请求结束（包括客户端断开）时，正在执行的语句会被取消
+func (r *resource) session(c *gee.Context) *session.Session {
+	return r.engine.NewSession().WithContext(c).Model(reflect.New(r.model).Interface())
+}
+
+// convert 将查询参数或者路径参数转换为字段的类型
+func (r *resource) convert(name string, value string) (interface{}, error) {
+	field, _ := r.model.FieldByName(name)
+	v := reflect.New(field.Type).Elem()
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return nil, gee.NewHTTPError(http.StatusBadRequest, "invalid %s: %v", name, err)
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return nil, gee.NewHTTPError(http.StatusBadRequest, "invalid %s: %v", name, err)
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return nil, gee.NewHTTPError(http.StatusBadRequest, "invalid %s: %v", name, err)
+		}
+		v.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, gee.NewHTTPError(http.StatusBadRequest, "invalid %s: %v", name, err)
+		}
+		v.SetBool(b)
+	default:
+		return nil, gee.NewHTTPError(http.StatusBadRequest, "field %s can't be used as a condition", name)
+	}
+	return v.Interface(), nil
+}
+
+// where 将白名单中的查询参数合并为一个WHERE条件，字段名来自白名单，值通过参数绑定，不会引入注入
+func (r *resource) where(c *gee.Context) (string, []interface{}, error) {
+	query := c.Req.URL.Query()
+	var conds []string
+	var args []interface{}
+	for _, field := range r.table.FieldNames {
+		if !r.filters[field] {
+			continue
+		}
+		if _, ok := query[field]; !ok {
+			continue
+		}
+		v, err := r.convert(field, query.Get(field))
+		if err != nil {
+			return "", nil, err
+		}
+		conds = append(conds, field+" = ?")
+		args = append(args, v)
+	}
+	return strings.Join(conds, " AND "), args, nil
+}
+
+// orderBy 解析order参数，只允许白名单中的字段
+func (r *resource) orderBy(c *gee.Context) (string, error) {
+	order := c.Query("order")
+	if order == "" {
+		return "", nil
+	}
+	var parts []string
+	for _, name := range strings.Split(order, ",") {
+		direction := "ASC"
+		if strings.HasPrefix(name, "-") {
+			name, direction = name[1:], "DESC"
+		}
+		if !r.orders[name] {
+			return "", gee.NewHTTPError(http.StatusBadRequest, "can't order by %s", name)
+		}
+		parts = append(parts, name+" "+direction)
+	}
+	return strings.Join(parts, ", "), nil
+}
+
+func (r *resource) list(c *gee.Context) {
+	page, err := c.QueryInt("page", 1)
+	if err != nil || page < 1 {
+		c.Error(gee.NewHTTPError(http.StatusBadRequest, "invalid page"))
+		return
+	}
+	size, err := c.QueryInt("size", r.opt.DefaultPageSize)
+	if err != nil || size < 1 || size > r.opt.MaxPageSize {
+		c.Error(gee.NewHTTPError(http.StatusBadRequest, "invalid size, should be between 1 and %d", r.opt.MaxPageSize))
+		return
+	}
+	where, args, err := r.where(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	order, err := r.orderBy(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// 每条语句执行之后session的子句都会被清空，因此Count和Find需要分别设置条件
+	s := r.session(c)
+	if where != "" {
+		s.Where(where, args...)
+	}
+	total, err := s.Count()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if where != "" {
+		s.Where(where, args...)
+	}
+	if order != "" {
+		s.OrderBy(order)
+	}
+	items := reflect.New(reflect.SliceOf(r.model))
+	items.Elem().Set(reflect.MakeSlice(reflect.SliceOf(r.model), 0, size))
+	if err := s.Limit(size).Offset((page - 1) * size).Find(items.Interface()); err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, &Page{Items: items.Elem().Interface(), Page: page, Size: size, Total: total})
+}
+
+func (r *resource) get(c *gee.Context) {
+	id, err := r.convert(r.pk, c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	value := reflect.New(r.model)
+	if err := r.session(c).Where(r.pk+" = ?", id).First(value.Interface()); err != nil {
+		if err == session.ErrNotFound {
+			err = gee.NewHTTPError(http.StatusNotFound, "%s %v not found", r.model.Name(), id)
+		}
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, value.Interface())
+}
+
+func (r *resource) create(c *gee.Context) {
+	value := reflect.New(r.model)
+	if err := c.Bind(value.Interface()); err != nil {
+		c.Error(err)
+		return
+	}
+	if _, err := r.session(c).Insert(value.Interface()); err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusCreated, value.Interface())
+}
+
+func (r *resource) update(c *gee.Context) {
+	id, err := r.convert(r.pk, c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	value := reflect.New(r.model)
+	if err := c.Bind(value.Interface()); err != nil {
+		c.Error(err)
+		return
+	}
+	// 主键以路径为准，更新其余所有字段
+	value.Elem().FieldByName(r.pk).Set(reflect.ValueOf(id))
+	fields := make(map[string]interface{})
+	for _, name := range r.table.FieldNames {
+		if name != r.pk {
+			fields[name] = value.Elem().FieldByName(name).Interface()
+		}
+	}
+	affected, err := r.session(c).Where(r.pk+" = ?", id).Update(fields)
+	if err == nil && affected == 0 {
+		err = gee.NewHTTPError(http.StatusNotFound, "%s %v not found", r.model.Name(), id)
+	}
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, value.Interface())
+}
+
+func (r *resource) delete(c *gee.Context) {
+	id, err := r.convert(r.pk, c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	affected, err := r.session(c).Where(r.pk+" = ?", id).Delete()
+	if err == nil && affected == 0 {
+		err = gee.NewHTTPError(http.StatusNotFound, "%s %v not found", r.model.Name(), id)
+	}
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,115 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"geektutu/geeorm"
+	"geektutu/geeweb/gee"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+type User struct {
+	ID   int    `geeorm:"PRIMARY KEY"`
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+func (u *User) Validate() error {
+	if u.Name == "" {
+		return errors.New("name is required")
+	}
+	return nil
+}
+
+func newTestEngine(t *testing.T) *gee.Engine {
+	t.Helper()
+	engine, err := geeorm.NewEngine("sqlite3", filepath.Join(t.TempDir(), "rest.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(engine.Close)
+	s := engine.NewSession().Model(&User{})
+	if err := s.CreateTable(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Insert(&User{1, "Tom", 18}, &User{2, "Sam", 25}, &User{3, "Jack", 25}); err != nil {
+		t.Fatal(err)
+	}
+
+	r := gee.New()
+	r.RedirectTrailingSlash = true
+	err = Register(r.Group("/users"), engine, &User{}, &Option{Filters: []string{"Age"}, Orders: []string{"Age", "Name"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestRegister(t *testing.T) {
+	r := newTestEngine(t)
+	testCases := []struct {
+		desc   string
+		method string
+		url    string
+		body   string
+		code   int
+		want   string
+	}{
+		{"分页", "GET", "/users?size=2&page=2&order=Name", "", 200,
+			`{"items":[{"ID":1,"name":"Tom","age":18}],"page":2,"size":2,"total":3}`},
+		{"过滤和排序", "GET", "/users?Age=25&order=-Name", "", 200,
+			`{"items":[{"ID":2,"name":"Sam","age":25},{"ID":3,"name":"Jack","age":25}],"page":1,"size":20,"total":2}`},
+		{"不在白名单中的过滤字段被忽略", "GET", "/users?Name=Tom&size=1&order=Age", "", 200,
+			`{"items":[{"ID":1,"name":"Tom","age":18}],"page":1,"size":1,"total":3}`},
+		{"不允许的排序字段", "GET", "/users?order=ID", "", 400, `{"error":"can't order by ID"}`},
+		{"size超过上限", "GET", "/users?size=1000", "", 400, `{"error":"invalid size, should be between 1 and 100"}`},
+		{"非法的过滤值", "GET", "/users?Age=x", "", 400, `"error":"invalid Age`},
+		{"获取", "GET", "/users/1", "", 200, `{"ID":1,"name":"Tom","age":18}`},
+		{"不存在", "GET", "/users/9", "", 404, `{"error":"User 9 not found"}`},
+		{"创建", "POST", "/users", `{"ID":4,"name":"Lily","age":20}`, 201, `{"ID":4,"name":"Lily","age":20}`},
+		{"校验失败", "POST", "/users", `{"ID":5,"age":20}`, 400, `{"error":"name is required"}`},
+		{"更新", "PUT", "/users/4", `{"name":"Lucy","age":21}`, 200, `{"ID":4,"name":"Lucy","age":21}`},
+		{"更新之后获取", "GET", "/users/4", "", 200, `{"ID":4,"name":"Lucy","age":21}`},
+		{"更新不存在的记录", "PUT", "/users/9", `{"name":"Lucy"}`, 404, `{"error":"User 9 not found"}`},
+		{"删除", "DELETE", "/users/4", "", 204, ``},
+		{"重复删除", "DELETE", "/users/4", "", 404, `{"error":"User 4 not found"}`},
+	}
+	for _, tc := range testCases {
+		req := httptest.NewRequest(tc.method, tc.url, strings.NewReader(tc.body))
+		if tc.body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.code || !strings.Contains(w.Body.String(), tc.want) {
+			t.Errorf("%s: %s %s got code=%d body=%q, want code=%d body containing %q",
+				tc.desc, tc.method, tc.url, w.Code, w.Body.String(), tc.code, tc.want)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/users", nil))
+	var page struct{ Total int }
+	if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil || page.Total != 3 {
+		t.Errorf("after delete got %q", w.Body.String())
+	}
+}
+
+func TestRegisterInvalid(t *testing.T) {
+	engine, _ := geeorm.NewEngine("sqlite3", filepath.Join(t.TempDir(), "rest.db"))
+	defer engine.Close()
+	r := gee.New()
+	type NoKey struct{ Name string }
+	if err := Register(r.Group("/a"), engine, &NoKey{}, nil); err == nil {
+		t.Error("model without primary key should fail")
+	}
+	if err := Register(r.Group("/b"), engine, &User{}, &Option{Filters: []string{"Email"}}); err == nil {
+		t.Error("unknown filter field should fail")
+	}
+}